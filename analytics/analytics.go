@@ -0,0 +1,148 @@
+// Package analytics computes statistics about the tour of an artist:
+// the distance travelled between consecutive concerts, the longest leg,
+// the countries and continents visited and the number of concerts per year.
+package analytics
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"groupie-tracker/gazetteer"
+)
+
+// earthRadiusKm is the mean radius of the Earth.
+const earthRadiusKm = 6371.0088
+
+// Stop is one concert of a tour.
+type Stop struct {
+	Date     time.Time
+	Location string          // upstream location slug
+	Place    gazetteer.Place // coordinates and country, valid when Resolved is set
+	Resolved bool
+}
+
+// Leg is the journey between two consecutive concert locations.
+type Leg struct {
+	From       gazetteer.Place `json:"from"`
+	To         gazetteer.Place `json:"to"`
+	Departure  time.Time       `json:"departure"` // date of the concert at From
+	Arrival    time.Time       `json:"arrival"`   // date of the concert at To
+	DistanceKm float64         `json:"distanceKm"`
+}
+
+// Region is a country or continent with the number of concerts played there.
+type Region struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	Concerts int    `json:"concerts"`
+}
+
+// YearCount is the number of concerts played in a year.
+type YearCount struct {
+	Year     int `json:"year"`
+	Concerts int `json:"concerts"`
+}
+
+// Summary holds the analytics of a tour.
+type Summary struct {
+	Concerts        int         `json:"concerts"`
+	Unresolved      int         `json:"unresolvedConcerts"` // concerts without coordinates
+	TotalDistanceKm float64     `json:"totalDistanceKm"`
+	Legs            []Leg       `json:"legs"`
+	LongestLeg      *Leg        `json:"longestLeg,omitempty"`
+	Countries       []Region    `json:"countries"`
+	Continents      []Region    `json:"continents"`
+	PerYear         []YearCount `json:"concertsPerYear"`
+}
+
+// Distance returns the great-circle distance between two places in kilometres.
+func Distance(a, b gazetteer.Place) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLat := lat2 - lat1
+	dLon := radians(b.Lon - a.Lon)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// Summarize computes the analytics of a tour. Stops may be given in any order;
+// legs follow the concerts chronologically and skip stops without coordinates.
+func Summarize(stops []Stop) Summary {
+	sorted := make([]Stop, len(stops))
+	copy(sorted, stops)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	summary := Summary{
+		Concerts:   len(sorted),
+		Legs:       []Leg{},
+		Countries:  []Region{},
+		Continents: []Region{},
+		PerYear:    []YearCount{},
+	}
+	countries := make(map[string]int)
+	continents := make(map[string]int)
+	years := make(map[int]int)
+
+	var previous *Stop
+	for i := range sorted {
+		stop := &sorted[i]
+		years[stop.Date.Year()]++
+		if !stop.Resolved {
+			summary.Unresolved++
+			continue
+		}
+		countries[stop.Place.Country]++
+		continents[stop.Place.Continent]++
+
+		if previous != nil && previous.Place.Slug != stop.Place.Slug {
+			leg := Leg{
+				From:       previous.Place,
+				To:         stop.Place,
+				Departure:  previous.Date,
+				Arrival:    stop.Date,
+				DistanceKm: Distance(previous.Place, stop.Place),
+			}
+			summary.Legs = append(summary.Legs, leg)
+			summary.TotalDistanceKm += leg.DistanceKm
+		}
+		previous = stop
+	}
+
+	for i := range summary.Legs {
+		if summary.LongestLeg == nil || summary.Legs[i].DistanceKm > summary.LongestLeg.DistanceKm {
+			summary.LongestLeg = &summary.Legs[i]
+		}
+	}
+
+	summary.Countries = regions(countries, gazetteer.CountryName)
+	summary.Continents = regions(continents, gazetteer.ContinentName)
+	for year, n := range years {
+		summary.PerYear = append(summary.PerYear, YearCount{Year: year, Concerts: n})
+	}
+	sort.Slice(summary.PerYear, func(i, j int) bool {
+		return summary.PerYear[i].Year < summary.PerYear[j].Year
+	})
+	return summary
+}
+
+// regions turns concert counts per code into regions, most concerts first.
+func regions(counts map[string]int, name func(string) string) []Region {
+	list := make([]Region, 0, len(counts))
+	for code, n := range counts {
+		list = append(list, Region{Code: code, Name: name(code), Concerts: n})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Concerts != list[j].Concerts {
+			return list[i].Concerts > list[j].Concerts
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
@@ -0,0 +1,97 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"groupie-tracker/gazetteer"
+)
+
+func place(t *testing.T, slug string) gazetteer.Place {
+	t.Helper()
+	p, ok := gazetteer.Default().Lookup(slug)
+	if !ok {
+		t.Fatalf("%s is not in the gazetteer", slug)
+	}
+	return p
+}
+
+func stop(t *testing.T, date, slug string) Stop {
+	t.Helper()
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, ok := gazetteer.Default().Lookup(slug)
+	return Stop{Date: d, Location: slug, Place: p, Resolved: ok}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		from, to string
+		km       float64
+	}{
+		{"paris-france", "london-uk", 344},
+		{"los_angeles-usa", "new_york-usa", 3936},
+		{"sydney-australia", "sydney-australia", 0},
+	}
+	for _, tt := range tests {
+		got := Distance(place(t, tt.from), place(t, tt.to))
+		// The gazetteer rounds coordinates to two decimals
+		if math.Abs(got-tt.km) > 5 {
+			t.Errorf("Distance(%s, %s) = %.0f km, want about %.0f km", tt.from, tt.to, got, tt.km)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	stops := []Stop{
+		stop(t, "2020-01-10", "london-uk"),
+		stop(t, "2019-12-01", "paris-france"),
+		stop(t, "2019-12-02", "paris-france"),
+		stop(t, "2020-02-01", "atlantis-ocean"),
+		stop(t, "2020-03-01", "new_york-usa"),
+	}
+
+	s := Summarize(stops)
+
+	if s.Concerts != 5 || s.Unresolved != 1 {
+		t.Errorf("Concerts = %d, Unresolved = %d, want 5 and 1", s.Concerts, s.Unresolved)
+	}
+	// Paris -> London -> New York; the second night in Paris is not a leg
+	if len(s.Legs) != 2 {
+		t.Fatalf("got %d legs, want 2", len(s.Legs))
+	}
+	if s.Legs[0].From.Slug != "paris-france" || s.Legs[0].To.Slug != "london-uk" {
+		t.Errorf("first leg = %s -> %s, want paris-france -> london-uk", s.Legs[0].From.Slug, s.Legs[0].To.Slug)
+	}
+	if !s.Legs[0].Departure.Equal(stops[2].Date) {
+		t.Errorf("first leg departs %v, want the second Paris date", s.Legs[0].Departure)
+	}
+	if s.LongestLeg == nil || s.LongestLeg.To.Slug != "new_york-usa" {
+		t.Errorf("LongestLeg = %+v, want London -> New York", s.LongestLeg)
+	}
+	if want := s.Legs[0].DistanceKm + s.Legs[1].DistanceKm; s.TotalDistanceKm != want {
+		t.Errorf("TotalDistanceKm = %v, want %v", s.TotalDistanceKm, want)
+	}
+
+	if len(s.Countries) != 3 || s.Countries[0].Code != "FR" || s.Countries[0].Concerts != 2 || s.Countries[0].Name != "France" {
+		t.Errorf("Countries = %+v, want France first with 2 concerts out of 3 countries", s.Countries)
+	}
+	if len(s.Continents) != 2 || s.Continents[0].Name != "Europe" {
+		t.Errorf("Continents = %+v, want Europe and North America", s.Continents)
+	}
+
+	expected := []YearCount{{2019, 2}, {2020, 3}}
+	if len(s.PerYear) != len(expected) || s.PerYear[0] != expected[0] || s.PerYear[1] != expected[1] {
+		t.Errorf("PerYear = %v, want %v", s.PerYear, expected)
+	}
+}
+
+func TestSummarize_Empty(t *testing.T) {
+	s := Summarize(nil)
+	if s.Concerts != 0 || s.LongestLeg != nil || s.TotalDistanceKm != 0 || s.Legs == nil {
+		t.Errorf("Summarize(nil) = %+v", s)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"groupie-tracker/server"
+)
+
+// runExport implements the export subcommand:
+//
+//	groupie-tracker export [-o file] <name> [key=value ...]
+//
+// where name is one of the export files served under /export/ and the
+// key=value pairs are the same filter parameters the web exports accept.
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := fs.String("o", "", "write the export to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: groupie-tracker export [-o file] <name> [key=value ...]")
+		fmt.Fprintln(fs.Output(), "exports:", strings.Join(server.ExportNames(), ", "))
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return fmt.Errorf("missing export name")
+	}
+
+	// Collect the filter parameters given as key=value pairs
+	query := url.Values{}
+	for _, arg := range fs.Args()[1:] {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return fmt.Errorf("invalid filter %q, want key=value", arg)
+		}
+		query.Add(key, value)
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	return server.WriteExport(out, fs.Arg(0), query)
+}
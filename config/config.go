@@ -0,0 +1,321 @@
+// Package config reads the settings of the groupie-tracker server.
+//
+// Each setting can come from, in increasing order of precedence: the defaults,
+// an optional JSON config file, a GROUPIE_* environment variable and a command
+// line flag. The config file is named by the -config flag or GROUPIE_CONFIG.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DayLayout is the format of the -now date.
+const DayLayout = "2006-01-02"
+
+// Duration is a time.Duration written as "90s" or "5m" in config files, flags and variables.
+type Duration time.Duration
+
+// Set parses a duration for the flag package.
+func (d *Duration) Set(s string) error {
+	v, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// UnmarshalJSON reads a duration string such as "10m".
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"10m\": %w", err)
+	}
+	return d.Set(s)
+}
+
+// MarshalJSON writes the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// SimilarityWeights sets how much each kind of resemblance counts when ranking related artists.
+// They can only be changed in the config file.
+type SimilarityWeights struct {
+	SharedCity     float64 `json:"sharedCity"`     // per concert location both artists played
+	CreationYear   float64 `json:"creationYear"`   // for being formed the same year
+	FirstAlbumYear float64 `json:"firstAlbumYear"` // for releasing their first album the same year
+	MemberCount    float64 `json:"memberCount"`    // for having the same number of members
+	SharedMember   float64 `json:"sharedMember"`   // per musician in both line-ups
+	YearWindow     int     `json:"yearWindow"`     // years apart at which the year scores drop to zero
+}
+
+// Config holds the server settings.
+type Config struct {
+	Addr            string            `json:"addr"`            // address the server listens on
+	UpstreamURL     string            `json:"upstreamURL"`     // base URL of the groupie trackers API
+	UpstreamTimeout Duration          `json:"upstreamTimeout"` // a whole request to the upstream API, body included
+	DataDir         string            `json:"dataDir"`         // local files such as the gazetteer overrides and the data snapshot
+	Dev             bool              `json:"dev"`             // read the templates and static files from disk, reloading the templates on change
+	TemplatesDir    string            `json:"templatesDir"`    // HTML templates, in development mode
+	StaticDir       string            `json:"staticDir"`       // files served under /static/, in development mode
+	CacheTTL        Duration          `json:"cacheTTL"`        // reuse of per-artist upstream responses, 0 to disable
+	StaticCacheTTL  Duration          `json:"staticCacheTTL"`  // browser caching of static files, 0 to disable
+	RefreshInterval Duration          `json:"refreshInterval"` // reload of the artists and concerts, 0 to disable
+	ReadyMaxAge     Duration          `json:"readyMaxAge"`     // age of the data at which /readyz fails, when refreshing
+	ReadTimeout     Duration          `json:"readTimeout"`     // reading a whole request, headers and body
+	WriteTimeout    Duration          `json:"writeTimeout"`    // from the end of the request headers to the end of the response
+	IdleTimeout     Duration          `json:"idleTimeout"`     // keep-alive connections waiting for the next request
+	ShutdownTimeout Duration          `json:"shutdownTimeout"` // draining in-flight requests on SIGINT or SIGTERM
+	LogLevel        string            `json:"logLevel"`        // debug, info, warn or error
+	Now             string            `json:"now"`             // yyyy-mm-dd treated as today, empty for the clock
+	Similarity      SimilarityWeights `json:"similarity"`
+}
+
+// Default returns the settings used when nothing else is given.
+func Default() Config {
+	return Config{
+		Addr:            ":3000",
+		UpstreamURL:     "https://groupietrackers.herokuapp.com/api",
+		UpstreamTimeout: Duration(10 * time.Second),
+		DataDir:         "data",
+		TemplatesDir:    "web/templates",
+		StaticDir:       "web/static",
+		CacheTTL:        Duration(10 * time.Minute),
+		StaticCacheTTL:  Duration(time.Hour),
+		RefreshInterval: Duration(time.Hour),
+		ReadyMaxAge:     Duration(3 * time.Hour),
+		ReadTimeout:     Duration(10 * time.Second),
+		WriteTimeout:    Duration(30 * time.Second),
+		IdleTimeout:     Duration(2 * time.Minute),
+		ShutdownTimeout: Duration(15 * time.Second),
+		LogLevel:        "info",
+		Similarity: SimilarityWeights{
+			SharedCity:     1,
+			CreationYear:   3,
+			FirstAlbumYear: 2,
+			MemberCount:    1,
+			SharedMember:   5,
+			YearWindow:     10,
+		},
+	}
+}
+
+// flagSet defines the command line flags, writing into cfg and configPath.
+func flagSet(cfg *Config, configPath *string) *flag.FlagSet {
+	fs := flag.NewFlagSet("groupie-tracker", flag.ContinueOnError)
+	fs.StringVar(configPath, "config", *configPath, "JSON config file (env GROUPIE_CONFIG)")
+	fs.StringVar(&cfg.Addr, "addr", cfg.Addr, "listen address (env GROUPIE_ADDR)")
+	fs.StringVar(&cfg.UpstreamURL, "upstream", cfg.UpstreamURL, "base URL of the upstream API (env GROUPIE_UPSTREAM_URL)")
+	fs.Var(&cfg.UpstreamTimeout, "upstream-timeout", "maximum duration of a request to the upstream API (env GROUPIE_UPSTREAM_TIMEOUT)")
+	fs.StringVar(&cfg.DataDir, "data-dir", cfg.DataDir, "directory of local data files (env GROUPIE_DATA_DIR)")
+	fs.BoolVar(&cfg.Dev, "dev", cfg.Dev, "read the templates and static files from disk and reload the templates when they change (env GROUPIE_DEV)")
+	fs.StringVar(&cfg.TemplatesDir, "templates", cfg.TemplatesDir, "directory of the HTML templates, with -dev (env GROUPIE_TEMPLATES_DIR)")
+	fs.StringVar(&cfg.StaticDir, "static", cfg.StaticDir, "directory of the static files, with -dev (env GROUPIE_STATIC_DIR)")
+	fs.Var(&cfg.CacheTTL, "cache-ttl", "how long upstream responses are reused, 0 to disable (env GROUPIE_CACHE_TTL)")
+	fs.Var(&cfg.StaticCacheTTL, "static-cache-ttl", "browser cache lifetime of static files, 0 to disable (env GROUPIE_STATIC_CACHE_TTL)")
+	fs.Var(&cfg.RefreshInterval, "refresh", "interval between data reloads, 0 to disable (env GROUPIE_REFRESH_INTERVAL)")
+	fs.Var(&cfg.ReadyMaxAge, "ready-max-age", "data age at which /readyz reports not ready, when refreshing (env GROUPIE_READY_MAX_AGE)")
+	fs.Var(&cfg.ReadTimeout, "read-timeout", "maximum duration of reading a request (env GROUPIE_READ_TIMEOUT)")
+	fs.Var(&cfg.WriteTimeout, "write-timeout", "maximum duration of writing a response (env GROUPIE_WRITE_TIMEOUT)")
+	fs.Var(&cfg.IdleTimeout, "idle-timeout", "how long idle keep-alive connections are kept (env GROUPIE_IDLE_TIMEOUT)")
+	fs.Var(&cfg.ShutdownTimeout, "shutdown-timeout", "how long in-flight requests may finish on shutdown (env GROUPIE_SHUTDOWN_TIMEOUT)")
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "debug, info, warn or error (env GROUPIE_LOG_LEVEL)")
+	fs.StringVar(&cfg.Now, "now", cfg.Now, "treat this day (yyyy-mm-dd) as today (env GROUPIE_NOW)")
+	return fs
+}
+
+// Load builds the configuration from the command line arguments, the environment
+// and the config file, and validates it. It returns the arguments left after the
+// flags, such as a subcommand.
+func Load(args []string, getenv func(string) string) (Config, []string, error) {
+	// A first pass only finds the config file, the flags are applied last
+	configPath := getenv("GROUPIE_CONFIG")
+	scratch := Default()
+	fs := flagSet(&scratch, &configPath)
+	if err := fs.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+
+	cfg := Default()
+	if configPath != "" {
+		if err := cfg.readFile(configPath); err != nil {
+			return Config{}, nil, err
+		}
+	}
+	if err := cfg.applyEnv(getenv); err != nil {
+		return Config{}, nil, err
+	}
+	fs = flagSet(&cfg, &configPath)
+	fs.SetOutput(io.Discard) // errors were already reported by the first pass
+	if err := fs.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+// readFile overrides the settings with those present in a JSON config file.
+func (c *Config) readFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not read config file: %w", err)
+	}
+	defer file.Close()
+
+	dec := json.NewDecoder(file)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv overrides the settings with the GROUPIE_* environment variables that are set.
+func (c *Config) applyEnv(getenv func(string) string) error {
+	values := map[string]*string{
+		"GROUPIE_ADDR":          &c.Addr,
+		"GROUPIE_UPSTREAM_URL":  &c.UpstreamURL,
+		"GROUPIE_DATA_DIR":      &c.DataDir,
+		"GROUPIE_TEMPLATES_DIR": &c.TemplatesDir,
+		"GROUPIE_STATIC_DIR":    &c.StaticDir,
+		"GROUPIE_LOG_LEVEL":     &c.LogLevel,
+		"GROUPIE_NOW":           &c.Now,
+	}
+	for name, target := range values {
+		if value := getenv(name); value != "" {
+			*target = value
+		}
+	}
+
+	durations := map[string]*Duration{
+		"GROUPIE_UPSTREAM_TIMEOUT": &c.UpstreamTimeout,
+		"GROUPIE_CACHE_TTL":        &c.CacheTTL,
+		"GROUPIE_STATIC_CACHE_TTL": &c.StaticCacheTTL,
+		"GROUPIE_REFRESH_INTERVAL": &c.RefreshInterval,
+		"GROUPIE_READY_MAX_AGE":    &c.ReadyMaxAge,
+		"GROUPIE_READ_TIMEOUT":     &c.ReadTimeout,
+		"GROUPIE_WRITE_TIMEOUT":    &c.WriteTimeout,
+		"GROUPIE_IDLE_TIMEOUT":     &c.IdleTimeout,
+		"GROUPIE_SHUTDOWN_TIMEOUT": &c.ShutdownTimeout,
+	}
+	var errs []error
+	for name, target := range durations {
+		if value := getenv(name); value != "" {
+			if err := target.Set(value); err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s: %w", name, err))
+			}
+		}
+	}
+	if value := getenv("GROUPIE_DEV"); value != "" {
+		dev, err := strconv.ParseBool(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid GROUPIE_DEV: %w", err))
+		}
+		c.Dev = dev
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports every invalid setting.
+func (c Config) Validate() error {
+	var errs []error
+	invalid := func(name string, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("invalid %s: %s", name, fmt.Sprintf(format, args...)))
+	}
+
+	if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		invalid("addr", "%v", err)
+	}
+	if u, err := url.Parse(c.UpstreamURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		invalid("upstream URL", "%q is not an http or https URL", c.UpstreamURL)
+	}
+	dirs := []struct{ name, dir string }{
+		{"data dir", c.DataDir},
+		{"templates dir", c.TemplatesDir},
+		{"static dir", c.StaticDir},
+	}
+	for _, d := range dirs {
+		if strings.TrimSpace(d.dir) == "" {
+			invalid(d.name, "must not be empty")
+		}
+	}
+	if c.CacheTTL < 0 {
+		invalid("cache TTL", "must not be negative")
+	}
+	if c.StaticCacheTTL < 0 {
+		invalid("static cache TTL", "must not be negative")
+	}
+	if c.RefreshInterval < 0 || (c.RefreshInterval > 0 && time.Duration(c.RefreshInterval) < time.Minute) {
+		invalid("refresh interval", "must be 0 or at least 1m, got %s", c.RefreshInterval)
+	}
+	if c.RefreshInterval > 0 && c.ReadyMaxAge <= c.RefreshInterval {
+		invalid("ready max age", "must be longer than the refresh interval %s, got %s", c.RefreshInterval, c.ReadyMaxAge)
+	}
+	timeouts := []struct {
+		name    string
+		timeout Duration
+	}{
+		{"upstream timeout", c.UpstreamTimeout},
+		{"read timeout", c.ReadTimeout},
+		{"write timeout", c.WriteTimeout},
+		{"idle timeout", c.IdleTimeout},
+		{"shutdown timeout", c.ShutdownTimeout},
+	}
+	for _, t := range timeouts {
+		if t.timeout <= 0 {
+			invalid(t.name, "must be positive, got %s", t.timeout)
+		}
+	}
+	if _, err := c.Level(); err != nil {
+		invalid("log level", "%q is not debug, info, warn or error", c.LogLevel)
+	}
+	if c.Now != "" {
+		if _, err := time.Parse(DayLayout, c.Now); err != nil {
+			invalid("now", "%q is not a yyyy-mm-dd date", c.Now)
+		}
+	}
+	w := c.Similarity
+	if w.SharedCity < 0 || w.CreationYear < 0 || w.FirstAlbumYear < 0 || w.MemberCount < 0 || w.SharedMember < 0 || w.YearWindow < 0 {
+		invalid("similarity weights", "must not be negative")
+	}
+	return errors.Join(errs...)
+}
+
+// Level returns the log level as a slog.Level.
+func (c Config) Level() (slog.Level, error) {
+	var level slog.Level
+	switch strings.ToLower(c.LogLevel) {
+	case "debug", "info", "warn", "error":
+		err := level.UnmarshalText([]byte(c.LogLevel))
+		return level, err
+	}
+	return level, fmt.Errorf("unknown log level %q", c.LogLevel)
+}
+
+// FixedNow returns the day set by Now, if any.
+func (c Config) FixedNow() (time.Time, bool) {
+	if c.Now == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(DayLayout, c.Now)
+	return t, err == nil
+}
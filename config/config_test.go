@@ -0,0 +1,150 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// env returns a getenv func reading from the map.
+func env(values map[string]string) func(string) string {
+	return func(name string) string { return values[name] }
+}
+
+// writeConfig writes a config file in a temporary directory and returns its path.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "groupie.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, args, err := Load(nil, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg != Default() {
+		t.Errorf("Load() = %+v, want the defaults", cfg)
+	}
+	if len(args) != 0 {
+		t.Errorf("Load() args = %v, want none", args)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfig(t, `{
+		"addr": ":4000",
+		"logLevel": "debug",
+		"cacheTTL": "1m",
+		"dataDir": "/srv/file",
+		"similarity": {"sharedMember": 7}
+	}`)
+	getenv := env(map[string]string{
+		"GROUPIE_CONFIG":    path,
+		"GROUPIE_ADDR":      ":5000",
+		"GROUPIE_CACHE_TTL": "2m",
+		"GROUPIE_DEV":       "true",
+	})
+
+	cfg, args, err := Load([]string{"-addr", ":6000", "export", "-o", "out.csv"}, getenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		got      interface{}
+		expected interface{}
+	}{
+		{"flag over env and file", cfg.Addr, ":6000"},
+		{"env over file", cfg.CacheTTL, Duration(2 * time.Minute)},
+		{"file over default", cfg.LogLevel, "debug"},
+		{"file over default", cfg.DataDir, "/srv/file"},
+		{"default", cfg.StaticDir, "web/static"},
+		{"env bool", cfg.Dev, true},
+		{"file weight", cfg.Similarity.SharedMember, 7.0},
+		{"weights left out of the file", cfg.Similarity.SharedCity, 1.0},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.expected {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.expected)
+		}
+	}
+	if strings.Join(args, " ") != "export -o out.csv" {
+		t.Errorf("Load() args = %v, want the subcommand and its flags", args)
+	}
+}
+
+func TestLoadConfigFlag(t *testing.T) {
+	flagPath := writeConfig(t, `{"addr": ":7000"}`)
+	envPath := writeConfig(t, `{"addr": ":8000"}`)
+
+	cfg, _, err := Load([]string{"-config", flagPath}, env(map[string]string{"GROUPIE_CONFIG": envPath}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != ":7000" {
+		t.Errorf("Addr = %s, want the one from the -config file", cfg.Addr)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		args     []string
+		env      map[string]string
+		expected []string
+	}{
+		{"Unknown file key", `{"adress": ":4000"}`, nil, nil, []string{"unknown field", "adress"}},
+		{"File duration", `{"cacheTTL": 60}`, nil, nil, []string{"duration"}},
+		{"Missing file", "", []string{"-config", "/nonexistent/groupie.json"}, nil, []string{"could not read config file"}},
+		{"Env duration", "", nil, map[string]string{"GROUPIE_REFRESH_INTERVAL": "hourly"}, []string{"GROUPIE_REFRESH_INTERVAL"}},
+		{"Env bool", "", nil, map[string]string{"GROUPIE_DEV": "sometimes"}, []string{"GROUPIE_DEV"}},
+		{"Unknown flag", "", []string{"-port", "80"}, nil, []string{"-port"}},
+		{"Several invalid settings", "", []string{"-addr", "3000", "-upstream", "ftp://example.com", "-log-level", "loud", "-refresh", "10s", "-now", "tomorrow"}, nil,
+			[]string{"invalid addr", "invalid upstream URL", "invalid log level", "invalid refresh interval", "invalid now"}},
+		{"Zero timeouts", `{"writeTimeout": "0s"}`, []string{"-shutdown-timeout", "0", "-upstream-timeout", "0"}, nil,
+			[]string{"invalid write timeout", "invalid shutdown timeout", "invalid upstream timeout"}},
+		{"Ready max age within a refresh", "", []string{"-refresh", "1h", "-ready-max-age", "30m"}, nil, []string{"invalid ready max age"}},
+		{"Negative weight", `{"similarity": {"sharedCity": -1}}`, nil, nil, []string{"similarity weights"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := map[string]string{}
+			for k, v := range tt.env {
+				values[k] = v
+			}
+			if tt.file != "" {
+				values["GROUPIE_CONFIG"] = writeConfig(t, tt.file)
+			}
+			_, _, err := Load(tt.args, env(values))
+			if err == nil {
+				t.Fatal("Load() succeeded, want an error")
+			}
+			for _, text := range tt.expected {
+				if !strings.Contains(err.Error(), text) {
+					t.Errorf("Load() error %q doesn't mention %q", err, text)
+				}
+			}
+		})
+	}
+}
+
+func TestFixedNow(t *testing.T) {
+	cfg := Default()
+	if _, ok := cfg.FixedNow(); ok {
+		t.Error("FixedNow() is set by default")
+	}
+	cfg.Now = "2021-03-04"
+	day, ok := cfg.FixedNow()
+	if !ok || day.Format(DayLayout) != "2021-03-04" {
+		t.Errorf("FixedNow() = %v, %v, want 2021-03-04", day, ok)
+	}
+}
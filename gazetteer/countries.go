@@ -0,0 +1,104 @@
+package gazetteer
+
+// continents maps the continent codes used in the data file to their names.
+var continents = map[string]string{
+	"AF": "Africa",
+	"AN": "Antarctica",
+	"AS": "Asia",
+	"EU": "Europe",
+	"NA": "North America",
+	"OC": "Oceania",
+	"SA": "South America",
+}
+
+// countries maps the ISO 3166-1 alpha-2 codes used in the data file to country names.
+var countries = map[string]string{
+	"AE": "United Arab Emirates",
+	"AR": "Argentina",
+	"AT": "Austria",
+	"AU": "Australia",
+	"BE": "Belgium",
+	"BG": "Bulgaria",
+	"BR": "Brazil",
+	"BY": "Belarus",
+	"CA": "Canada",
+	"CH": "Switzerland",
+	"CL": "Chile",
+	"CN": "China",
+	"CO": "Colombia",
+	"CR": "Costa Rica",
+	"CU": "Cuba",
+	"CZ": "Czechia",
+	"DE": "Germany",
+	"DK": "Denmark",
+	"EC": "Ecuador",
+	"EE": "Estonia",
+	"EG": "Egypt",
+	"ES": "Spain",
+	"FI": "Finland",
+	"FR": "France",
+	"GB": "United Kingdom",
+	"GR": "Greece",
+	"HK": "Hong Kong",
+	"HR": "Croatia",
+	"HU": "Hungary",
+	"ID": "Indonesia",
+	"IE": "Ireland",
+	"IL": "Israel",
+	"IN": "India",
+	"IT": "Italy",
+	"JM": "Jamaica",
+	"JP": "Japan",
+	"KR": "South Korea",
+	"LT": "Lithuania",
+	"LV": "Latvia",
+	"MX": "Mexico",
+	"MY": "Malaysia",
+	"NC": "New Caledonia",
+	"NL": "Netherlands",
+	"NO": "Norway",
+	"NZ": "New Zealand",
+	"PA": "Panama",
+	"PE": "Peru",
+	"PF": "French Polynesia",
+	"PH": "Philippines",
+	"PL": "Poland",
+	"PR": "Puerto Rico",
+	"PT": "Portugal",
+	"PY": "Paraguay",
+	"QA": "Qatar",
+	"RO": "Romania",
+	"RS": "Serbia",
+	"RU": "Russia",
+	"SA": "Saudi Arabia",
+	"SE": "Sweden",
+	"SG": "Singapore",
+	"SI": "Slovenia",
+	"SK": "Slovakia",
+	"TH": "Thailand",
+	"TR": "Turkey",
+	"TW": "Taiwan",
+	"UA": "Ukraine",
+	"US": "United States",
+	"UY": "Uruguay",
+	"VE": "Venezuela",
+	"ZA": "South Africa",
+}
+
+// CountryName returns the English name of an ISO 3166-1 alpha-2 country code,
+// or the code itself when it is not known.
+func CountryName(code string) string {
+	if name, ok := countries[code]; ok {
+		return name
+	}
+	return code
+}
+
+// ContinentName returns the name of a continent code such as "EU",
+// or the code itself when it is not known.
+func ContinentName(code string) string {
+	if name, ok := continents[code]; ok {
+		return name
+	}
+	return code
+}
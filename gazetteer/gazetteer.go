@@ -0,0 +1,195 @@
+// Package gazetteer maps the location slugs used by the upstream API, such as
+// "los_angeles-usa", to a city, ISO 3166-1 country, continent, coordinates and
+// time zone. The data ships with the binary, so lookups work offline.
+//
+// Slugs missing from the bundled data can be fixed locally with an override
+// file in the same CSV format; its rows replace or extend the bundled ones.
+package gazetteer
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // validate time zones without relying on the system database
+)
+
+//go:embed gazetteer.csv
+var data []byte
+
+// Header is the header row of the data and override files.
+var Header = []string{"slug", "city", "country", "continent", "lat", "lon", "timezone"}
+
+// Place is a location known to the gazetteer.
+type Place struct {
+	Slug      string  `json:"slug"`
+	City      string  `json:"city"`
+	Country   string  `json:"country"`   // ISO 3166-1 alpha-2 code, e.g. "US"
+	Continent string  `json:"continent"` // continent code, e.g. "NA"
+	Lat       float64 `json:"lat"`
+	Lon       float64 `json:"lon"`
+	TimeZone  string  `json:"timezone"` // IANA time zone, e.g. "America/Los_Angeles"
+}
+
+// CountryName returns the English name of the country of the place.
+func (p Place) CountryName() string {
+	return CountryName(p.Country)
+}
+
+// Gazetteer resolves location slugs to places.
+type Gazetteer struct {
+	places map[string]Place
+}
+
+var (
+	defaultOnce sync.Once
+	defaultGaz  *Gazetteer
+)
+
+// Default returns the gazetteer built from the bundled data file.
+func Default() *Gazetteer {
+	defaultOnce.Do(func() {
+		g, err := Parse(bytes.NewReader(data))
+		if err != nil {
+			panic("gazetteer: invalid bundled data: " + err.Error())
+		}
+		defaultGaz = g
+	})
+	return defaultGaz
+}
+
+// Load returns the bundled gazetteer with the rows of the override file at path applied.
+// A missing override file is not an error.
+func Load(path string) (*Gazetteer, error) {
+	g := &Gazetteer{places: make(map[string]Place)}
+	for slug, place := range Default().places {
+		g.places[slug] = place
+	}
+	if path == "" {
+		return g, nil
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return g, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	overrides, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for slug, place := range overrides.places {
+		g.places[slug] = place
+	}
+	return g, nil
+}
+
+// Parse reads a gazetteer from CSV with the columns listed in Header.
+// The first row is a header and is skipped.
+func Parse(r io.Reader) (*Gazetteer, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(Header)
+	cr.Comment = '#'
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Gazetteer{places: make(map[string]Place)}
+	for i, record := range records {
+		if i == 0 {
+			continue
+		}
+		place, err := parsePlace(record)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+		g.places[place.Slug] = place
+	}
+	return g, nil
+}
+
+// parsePlace validates one data row.
+func parsePlace(record []string) (Place, error) {
+	place := Place{
+		Slug:      Normalize(record[0]),
+		City:      strings.TrimSpace(record[1]),
+		Country:   strings.ToUpper(strings.TrimSpace(record[2])),
+		Continent: strings.ToUpper(strings.TrimSpace(record[3])),
+		TimeZone:  strings.TrimSpace(record[6]),
+	}
+	if place.Slug == "" || place.City == "" {
+		return place, errors.New("slug and city are required")
+	}
+	if len(place.Country) != 2 {
+		return place, fmt.Errorf("invalid country code %q", place.Country)
+	}
+	if _, ok := continents[place.Continent]; !ok {
+		return place, fmt.Errorf("invalid continent code %q", place.Continent)
+	}
+
+	var err error
+	if place.Lat, err = strconv.ParseFloat(strings.TrimSpace(record[4]), 64); err != nil {
+		return place, fmt.Errorf("invalid latitude: %w", err)
+	}
+	if place.Lon, err = strconv.ParseFloat(strings.TrimSpace(record[5]), 64); err != nil {
+		return place, fmt.Errorf("invalid longitude: %w", err)
+	}
+	if place.Lat < -90 || place.Lat > 90 || place.Lon < -180 || place.Lon > 180 {
+		return place, errors.New("coordinates out of range")
+	}
+	if _, err := time.LoadLocation(place.TimeZone); err != nil || place.TimeZone == "" {
+		return place, fmt.Errorf("invalid time zone %q", place.TimeZone)
+	}
+	return place, nil
+}
+
+// Lookup returns the place for a location slug.
+func (g *Gazetteer) Lookup(slug string) (Place, bool) {
+	place, ok := g.places[Normalize(slug)]
+	return place, ok
+}
+
+// Len returns the number of places in the gazetteer.
+func (g *Gazetteer) Len() int {
+	return len(g.places)
+}
+
+// Unresolved returns the distinct slugs the gazetteer cannot resolve, sorted.
+func (g *Gazetteer) Unresolved(slugs []string) []string {
+	seen := make(map[string]bool)
+	var missing []string
+	for _, slug := range slugs {
+		slug = Normalize(slug)
+		if _, ok := g.places[slug]; ok || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		missing = append(missing, slug)
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// Normalize brings a location slug into the canonical form used as key:
+// lower case, words joined by underscores and city and country separated by a hyphen.
+func Normalize(slug string) string {
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	slug = strings.Join(strings.FieldsFunc(slug, func(r rune) bool {
+		return r == ' ' || r == '_'
+	}), "_")
+	return strings.ReplaceAll(strings.ReplaceAll(slug, "_-", "-"), "-_", "-")
+}
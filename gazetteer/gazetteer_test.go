@@ -0,0 +1,126 @@
+package gazetteer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const header = "slug,city,country,continent,lat,lon,timezone\n"
+
+func TestDefault(t *testing.T) {
+	g := Default()
+	if g.Len() == 0 {
+		t.Fatal("Default() returned an empty gazetteer")
+	}
+
+	place, ok := g.Lookup("los_angeles-usa")
+	if !ok {
+		t.Fatal("Lookup(los_angeles-usa) found nothing")
+	}
+	if place.City != "Los Angeles" || place.Country != "US" || place.Continent != "NA" || place.TimeZone != "America/Los_Angeles" {
+		t.Errorf("Lookup(los_angeles-usa) = %+v", place)
+	}
+	if place.Lat < 33 || place.Lat > 35 || place.Lon > -117 || place.Lon < -119 {
+		t.Errorf("Lookup(los_angeles-usa) coordinates = %v, %v", place.Lat, place.Lon)
+	}
+	if place.CountryName() != "United States" {
+		t.Errorf("CountryName() = %q, want United States", place.CountryName())
+	}
+}
+
+func TestDefault_KnownCountries(t *testing.T) {
+	for slug, place := range Default().places {
+		if _, ok := countries[place.Country]; !ok {
+			t.Errorf("%s: country %q has no name", slug, place.Country)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, expected string
+	}{
+		{"los_angeles-usa", "los_angeles-usa"},
+		{" Los Angeles-USA ", "los_angeles-usa"},
+		{"los__angeles_-_usa", "los_angeles-usa"},
+		{"New York-usa", "new_york-usa"},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.expected {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.expected)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		row     string
+		wantErr bool
+	}{
+		{"Valid", "paris-france,Paris,FR,EU,48.86,2.35,Europe/Paris", false},
+		{"Invalid latitude", "paris-france,Paris,FR,EU,north,2.35,Europe/Paris", true},
+		{"Out of range", "paris-france,Paris,FR,EU,148.86,2.35,Europe/Paris", true},
+		{"Invalid country", "paris-france,Paris,France,EU,48.86,2.35,Europe/Paris", true},
+		{"Invalid continent", "paris-france,Paris,FR,XX,48.86,2.35,Europe/Paris", true},
+		{"Invalid time zone", "paris-france,Paris,FR,EU,48.86,2.35,Europe/Nowhere", true},
+		{"Missing column", "paris-france,Paris,FR,EU,48.86,2.35", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(header + tt.row + "\n"))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overrides.csv")
+	overrides := header +
+		"# Stade de France\n" +
+		"saint_denis-france,Saint-Denis,FR,EU,48.92,2.36,Europe/Paris\n" +
+		"los_angeles-usa,LA,US,NA,34.05,-118.24,America/Los_Angeles\n"
+	if err := os.WriteFile(path, []byte(overrides), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if g.Len() != Default().Len()+1 {
+		t.Errorf("Load() has %d places, want %d", g.Len(), Default().Len()+1)
+	}
+	if place, ok := g.Lookup("saint_denis-france"); !ok || place.City != "Saint-Denis" {
+		t.Errorf("override was not added: %+v", place)
+	}
+	if place, _ := g.Lookup("los_angeles-usa"); place.City != "LA" {
+		t.Errorf("override did not replace the bundled row: %+v", place)
+	}
+	if place, _ := Default().Lookup("los_angeles-usa"); place.City != "Los Angeles" {
+		t.Errorf("override leaked into the default gazetteer: %+v", place)
+	}
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	g, err := Load(filepath.Join(t.TempDir(), "missing.csv"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if g.Len() != Default().Len() {
+		t.Errorf("Load() has %d places, want %d", g.Len(), Default().Len())
+	}
+}
+
+func TestUnresolved(t *testing.T) {
+	slugs := []string{"paris-france", "atlantis-ocean", "Atlantis-Ocean", "el_dorado-colombia"}
+	expected := []string{"atlantis-ocean", "el_dorado-colombia"}
+	if got := Default().Unresolved(slugs); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unresolved() = %v, want %v", got, expected)
+	}
+}
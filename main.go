@@ -1,21 +1,121 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"groupie-tracker/config"
 	"groupie-tracker/server"
 )
 
 func main() {
-	http.HandleFunc("/static/", server.ServeStatic)
-	http.HandleFunc("/", server.MainPage)
-	http.HandleFunc("/artists/", server.InfoAboutArtist)
-	http.HandleFunc("/search/", server.SearchPage)
-	fmt.Println("Server running on http://localhost:3000/")
-	err := http.ListenAndServe(":3000", nil)
+	cfg, args, err := config.Load(os.Args[1:], os.Getenv)
 	if err != nil {
-		log.Fatal(err)
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		fatal("invalid configuration", "err", err)
 	}
+	// Every record, from the access log to the errors, goes through this handler
+	level, _ := cfg.Level()
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+
+	server.Configure(cfg)
+	if err := server.Load(); err != nil {
+		fatal("could not start", "err", err)
+	}
+
+	// Run a subcommand instead of the server when one is given
+	if len(args) > 0 {
+		if !server.DataLoaded() {
+			fatal("cannot run the command without the concert data", "command", args[0])
+		}
+		switch args[0] {
+		case "export":
+			err = runExport(args[1:])
+		case "gazetteer":
+			err = server.WriteGazetteerReport(os.Stdout)
+		default:
+			fatal("unknown command", "command", args[0])
+		}
+		if err != nil {
+			fatal("command failed", "command", args[0], "err", err)
+		}
+		return
+	}
+
+	if err := serve(cfg); err != nil {
+		fatal("server failed", "err", err)
+	}
+}
+
+// fatal logs the error and exits with a failure status.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// serve runs the server and the data refresher until SIGINT or SIGTERM, then lets
+// the requests in flight finish within the shutdown timeout.
+func serve(cfg config.Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: time.Duration(cfg.ReadTimeout),
+		ReadTimeout:       time.Duration(cfg.ReadTimeout),
+		WriteTimeout:      time.Duration(cfg.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.IdleTimeout),
+	}
+
+	if cfg.Dev {
+		go server.WatchTemplates(ctx)
+	}
+
+	// Without a refresh interval the refresher only retries until the upstream API answered
+	refresher := make(chan struct{})
+	go func() {
+		defer close(refresher)
+		server.RunRefresher(ctx, time.Duration(cfg.RefreshInterval))
+	}()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		fmt.Printf("Server running on %s\n", cfg.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		stop()
+		<-refresher
+		return err
+	case <-ctx.Done():
+	}
+	// A second signal kills the process right away
+	stop()
+	slog.Info("shutting down, waiting for the requests in flight")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout))
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	select {
+	case <-refresher:
+	case <-shutdownCtx.Done():
+		return errors.New("shutdown: the data refresh did not stop in time")
+	}
+	slog.Info("server stopped")
+	return nil
 }
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// safeBuffer collects the output of the server process while the test reads it.
+type safeBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *safeBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *safeBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// slowUpstream serves one artist and takes delay to answer for its locations,
+// sending on started when such a request arrives.
+func slowUpstream(t *testing.T, delay time.Duration, started chan<- struct{}) *httptest.Server {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := "http://" + r.Host
+		switch r.URL.Path {
+		case "/artists":
+			fmt.Fprintf(w, `[{"id": 1, "name": "Test Artist", "members": ["Test Member"], "creationDate": 1970,
+				"firstAlbum": "14-12-1973", "locations": "%[1]s/locations/1", "concertDates": "%[1]s/dates/1",
+				"relations": "%[1]s/relation/1"}]`, base)
+		case "/relation":
+			fmt.Fprint(w, `{"index": [{"id": 1, "datesLocations": {"london-uk": ["01-01-2021"]}}]}`)
+		case "/relation/1":
+			fmt.Fprint(w, `{"id": 1, "datesLocations": {"london-uk": ["01-01-2021"]}}`)
+		case "/locations/1":
+			started <- struct{}{}
+			time.Sleep(delay)
+			fmt.Fprint(w, `{"locations": ["london-uk"]}`)
+		case "/dates/1":
+			fmt.Fprint(w, `{"dates": ["*01-01-2021"]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+// freeAddr returns a local address nothing listens on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// TestGracefulShutdown builds the server, sends it SIGTERM during a request and
+// checks that the request still completes and the process exits cleanly.
+func TestGracefulShutdown(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and runs the server")
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGTERM is not available on Windows")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+
+	binary := filepath.Join(t.TempDir(), "groupie-tracker")
+	if out, err := exec.Command(goTool, "build", "-o", binary, ".").CombinedOutput(); err != nil {
+		t.Fatalf("build failed: %v\n%s", err, out)
+	}
+
+	started := make(chan struct{}, 1)
+	upstream := slowUpstream(t, time.Second, started)
+	addr := freeAddr(t)
+
+	var output safeBuffer
+	cmd := exec.Command(binary, "-addr", addr, "-upstream", upstream.URL, "-data-dir", t.TempDir(),
+		"-refresh", "1m", "-shutdown-timeout", "5s")
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	t.Cleanup(func() {
+		if cmd.ProcessState == nil {
+			cmd.Process.Kill()
+			<-exited
+		}
+	})
+
+	base := "http://" + addr
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		response, err := http.Get(base + "/")
+		if err == nil {
+			response.Body.Close()
+			if response.StatusCode == http.StatusOK {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not start: %v\n%s", err, output.String())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	type result struct {
+		code int
+		body string
+		err  error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		response, err := http.Get(base + "/artists/test-artist")
+		if err != nil {
+			inFlight <- result{err: err}
+			return
+		}
+		defer response.Body.Close()
+		var body bytes.Buffer
+		body.ReadFrom(response.Body)
+		inFlight <- result{code: response.StatusCode, body: body.String()}
+	}()
+
+	// Signal while the artist page waits for the upstream
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the artist page never reached the upstream\n%s", output.String())
+	}
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+
+	got := <-inFlight
+	if got.err != nil || got.code != http.StatusOK || !strings.Contains(got.body, "Test Artist") {
+		t.Errorf("in-flight request = %d, %v, want the artist page", got.code, got.err)
+	}
+
+	select {
+	case err := <-exited:
+		if err != nil {
+			t.Errorf("server exited with %v\n%s", err, output.String())
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("server did not exit after SIGTERM\n%s", output.String())
+	}
+	if !strings.Contains(output.String(), "server stopped") {
+		t.Errorf("output doesn't report the shutdown:\n%s", output.String())
+	}
+	if _, err := http.Get(base + "/"); err == nil {
+		t.Error("server still accepts connections after the shutdown")
+	}
+}
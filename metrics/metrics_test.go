@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounter("app_requests_total", "Requests served.", "route", "status")
+	artists := r.NewGauge("app_artists", "Artists loaded.")
+	r.NewGaugeFunc("app_ratio", "A computed ratio.", func() float64 { return 0.25 })
+	latency := r.NewHistogram("app_latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+
+	requests.Inc("/", "200")
+	requests.Add(2, "/", "200")
+	requests.Inc("/a", "404")
+	artists.Set(52)
+	latency.Observe(0.05, "/")
+	latency.Observe(0.1, "/")
+	latency.Observe(3, "/")
+
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# HELP app_requests_total Requests served.
+# TYPE app_requests_total counter
+app_requests_total{route="/",status="200"} 3
+app_requests_total{route="/a",status="404"} 1
+# HELP app_artists Artists loaded.
+# TYPE app_artists gauge
+app_artists 52
+# HELP app_ratio A computed ratio.
+# TYPE app_ratio gauge
+app_ratio 0.25
+# HELP app_latency_seconds Latency.
+# TYPE app_latency_seconds histogram
+app_latency_seconds_bucket{route="/",le="0.1"} 2
+app_latency_seconds_bucket{route="/",le="1"} 2
+app_latency_seconds_bucket{route="/",le="+Inf"} 3
+app_latency_seconds_sum{route="/"} 3.15
+app_latency_seconds_count{route="/"} 3
+`
+	if b.String() != expected {
+		t.Errorf("WriteText() =\n%s\nwant\n%s", b.String(), expected)
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("app_total", "Help with a \\ and a\nnew line.", "path")
+	c.Inc(`say "hi"` + "\n\\")
+
+	var b strings.Builder
+	r.WriteText(&b)
+	for _, line := range []string{
+		`# HELP app_total Help with a \\ and a\nnew line.`,
+		`app_total{path="say \"hi\"\n\\"} 1`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("output doesn't contain %s:\n%s", line, b.String())
+		}
+	}
+}
+
+func TestValues(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("c_total", "", "result")
+	g := r.NewGauge("g", "")
+	h := r.NewHistogram("h", "", DefaultBuckets)
+
+	c.Inc("hit")
+	c.Inc("hit")
+	g.Set(-1.5)
+	g.Set(7)
+	h.Observe(0.2)
+
+	if c.Value("hit") != 2 || c.Value("miss") != 0 {
+		t.Errorf("counter = %v hits, %v misses, want 2 and 0", c.Value("hit"), c.Value("miss"))
+	}
+	if g.Value() != 7 {
+		t.Errorf("gauge = %v, want 7", g.Value())
+	}
+	if h.Count() != 1 {
+		t.Errorf("histogram count = %d, want 1", h.Count())
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{0, "0"},
+		{1e6, "1e+06"},
+		{0.005, "0.005"},
+		{math.Inf(1), "+Inf"},
+		{math.NaN(), "NaN"},
+	}
+	for _, tt := range tests {
+		if got := formatValue(tt.value); got != tt.expected {
+			t.Errorf("formatValue(%v) = %q, want %q", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestMisuse(t *testing.T) {
+	tests := []struct {
+		name string
+		f    func(r *Registry)
+	}{
+		{"Missing label value", func(r *Registry) { r.NewCounter("a_total", "", "route").Inc() }},
+		{"Decreasing counter", func(r *Registry) { r.NewCounter("b_total", "").Add(-1) }},
+		{"Unsorted buckets", func(r *Registry) { r.NewHistogram("c", "", []float64{1, 0.5}) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("no panic")
+				}
+			}()
+			tt.f(NewRegistry())
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+
+	"groupie-tracker/analytics"
+	"groupie-tracker/gazetteer"
+)
+
+// apiArtist is the JSON representation of an artist with its concerts and tour analytics.
+type apiArtist struct {
+	Artist
+	Concerts  []concertRecord   `json:"concerts"`
+	Analytics analytics.Summary `json:"analytics"`
+}
+
+// apiErrorBody is the JSON body of API error responses.
+type apiErrorBody struct {
+	Status int    `json:"status"`
+	Error  string `json:"error"`
+}
+
+// writeJSON writes v as the JSON response with the given status code. It is
+// encoded before the status code is sent, so that a failure can still be a 500.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		slog.Error("could not encode the JSON response", "id", w.Header().Get(requestIDHeader), "err", err)
+		apiError(w, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(body.Bytes())
+}
+
+// apiError writes a JSON error response.
+func apiError(w http.ResponseWriter, code int) {
+	writeJSON(w, code, apiErrorBody{Status: code, Error: http.StatusText(code)})
+}
+
+// APIArtists lists the artists matching the filter parameters as JSON.
+func APIArtists(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		loggerFrom(r.Context()).Warn("invalid filter", "err", err)
+		apiError(w, http.StatusBadRequest)
+		return
+	}
+	matched, _, err := currentData().filterArtists(r.Context(), filter)
+	if err != nil {
+		loggerFrom(r.Context()).Error("could not filter the artists", "err", err)
+		apiError(w, http.StatusInternalServerError)
+		return
+	}
+	if matched == nil {
+		matched = []Artist{}
+	}
+	writeJSON(w, http.StatusOK, matched)
+}
+
+// APIArtist serves one artist with its concerts and tour analytics as JSON.
+func APIArtist(w http.ResponseWriter, r *http.Request) {
+	d := currentData()
+	artist, err := d.artistFromParam(r.PathValue("id"))
+	if err != nil {
+		loggerFrom(r.Context()).Warn("unknown artist", "err", err)
+		apiError(w, http.StatusNotFound)
+		return
+	}
+	rel, err := d.relationFor(r.Context(), artist)
+	if err != nil {
+		loggerFrom(r.Context()).Error("could not load the concerts", "artist", artist.Name, "err", err)
+		apiError(w, http.StatusInternalServerError)
+		return
+	}
+
+	concerts := concertsOf(artist, rel)
+	data := apiArtist{
+		Artist:    artist,
+		Concerts:  []concertRecord{},
+		Analytics: tourAnalytics(concerts),
+	}
+	for _, c := range concerts {
+		data.Concerts = append(data.Concerts, newConcertRecord(c))
+	}
+	writeJSON(w, http.StatusOK, data)
+}
+
+// apiNearbyConcert is a concert of a nearby search with its distance from the city.
+type apiNearbyConcert struct {
+	concertRecord
+	DistanceKm float64 `json:"distanceKm"`
+}
+
+// apiNearby is the JSON representation of a nearby search.
+type apiNearby struct {
+	City     gazetteer.Place    `json:"city"`
+	RadiusKm float64            `json:"radiusKm"`
+	From     string             `json:"from,omitempty"`
+	To       string             `json:"to,omitempty"`
+	Concerts []apiNearbyConcert `json:"concerts"`
+}
+
+// APINearby lists the concerts within a radius of a city as JSON, taking the parameters of /nearby.
+func APINearby(w http.ResponseWriter, r *http.Request) {
+	q, bad := parseNearbyQuery(r.URL.Query())
+	if bad != nil {
+		loggerFrom(r.Context()).Warn("invalid nearby search", "detail", bad.detail)
+		writeJSON(w, bad.code, apiErrorBody{Status: bad.code, Error: bad.detail})
+		return
+	}
+	concerts, err := currentData().allConcerts(r.Context())
+	if err != nil {
+		loggerFrom(r.Context()).Error("could not list the concerts", "err", err)
+		apiError(w, http.StatusInternalServerError)
+		return
+	}
+
+	data := apiNearby{City: q.Center, RadiusKm: q.RadiusKm, Concerts: []apiNearbyConcert{}}
+	if !q.From.IsZero() {
+		data.From = q.From.Format(dayLayout)
+	}
+	if !q.To.IsZero() {
+		data.To = q.To.Format(dayLayout)
+	}
+	for _, c := range nearbyConcerts(concerts, q) {
+		data.Concerts = append(data.Concerts, apiNearbyConcert{
+			concertRecord: newConcertRecord(c.Concert),
+			DistanceKm:    math.Round(c.DistanceKm*10) / 10,
+		})
+	}
+	writeJSON(w, http.StatusOK, data)
+}
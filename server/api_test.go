@@ -0,0 +1,102 @@
+package server
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"groupie-tracker/gazetteer"
+)
+
+func TestAPIArtists(t *testing.T) {
+	setupConcertData()
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedCode  int
+		expectedCount int
+	}{
+		{"All artists", "", http.StatusOK, 2},
+		{"Filtered", "?created_to=1968", http.StatusOK, 1},
+		{"No match", "?q=nobody", http.StatusOK, 0},
+		{"Invalid filter", "?ids=one", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/artists"+tt.query, nil)
+			w := httptest.NewRecorder()
+			APIArtists(w, r)
+			if w.Code != tt.expectedCode {
+				t.Fatalf("APIArtists() status code = %d, want %d", w.Code, tt.expectedCode)
+			}
+			if tt.expectedCode != http.StatusOK {
+				var body apiErrorBody
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Status != tt.expectedCode {
+					t.Errorf("error body = %s, want status %d", w.Body.String(), tt.expectedCode)
+				}
+				return
+			}
+			var list []Artist
+			if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+				t.Fatalf("response is not valid JSON: %v", err)
+			}
+			if len(list) != tt.expectedCount {
+				t.Errorf("got %d artists, want %d", len(list), tt.expectedCount)
+			}
+		})
+	}
+}
+
+func TestAPIArtist(t *testing.T) {
+	setupConcertData()
+	places = gazetteer.Default()
+
+	r := httptest.NewRequest(http.MethodGet, "/api/artists/1", nil)
+	r.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+	APIArtist(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("APIArtist() status code = %d, want %d", w.Code, http.StatusOK)
+	}
+	var data apiArtist
+	if err := json.Unmarshal(w.Body.Bytes(), &data); err != nil {
+		t.Fatalf("response is not valid JSON: %v", err)
+	}
+	if data.Name != "Queen" || len(data.Concerts) != 3 {
+		t.Errorf("got %s with %d concerts, want Queen with 3", data.Name, len(data.Concerts))
+	}
+	if data.Analytics.Concerts != 3 || data.Analytics.Unresolved != 1 {
+		t.Errorf("analytics = %+v, want 3 concerts with 1 unresolved", data.Analytics)
+	}
+	if len(data.Analytics.PerYear) != 2 {
+		t.Errorf("concerts per year = %v, want 2019 and 2020", data.Analytics.PerYear)
+	}
+}
+
+func TestAPIArtist_NotFound(t *testing.T) {
+	setupConcertData()
+
+	r := httptest.NewRequest(http.MethodGet, "/api/artists/42", nil)
+	r.SetPathValue("id", "42")
+	w := httptest.NewRecorder()
+	APIArtist(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("APIArtist() status code = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestWriteJSON_EncodeError(t *testing.T) {
+	captureLogs(t)
+	w := httptest.NewRecorder()
+	writeJSON(w, http.StatusOK, math.NaN())
+
+	var body apiErrorBody
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusInternalServerError || body.Status != http.StatusInternalServerError {
+		t.Errorf("writeJSON() of an unencodable value = %d %s, want a 500 error", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CalendarDay is a cell of the month grid.
+type CalendarDay struct {
+	Date     time.Time
+	InMonth  bool // false for the days of the neighbouring months that fill the first and last weeks
+	Today    bool
+	Concerts []Concert
+}
+
+// CalendarMonth is a month grid of concerts, one row per week starting on Monday.
+type CalendarMonth struct {
+	Heading  string
+	Month    string // yyyy-mm
+	IDs      string
+	Country  string
+	Concerts int
+	Prev     PageLink
+	Next     PageLink
+	Weeks    [][]CalendarDay
+}
+
+// inCountry reports whether a concert location is in the country given by ISO code or name.
+func inCountry(slug, country string) bool {
+	if place, ok := places.Lookup(slug); ok && strings.EqualFold(place.Country, country) {
+		return true
+	}
+	return strings.EqualFold(locationCountry(slug), country)
+}
+
+// calendarLink returns the calendar path of a month, keeping the other query parameters.
+func calendarLink(month time.Time, query url.Values) PageLink {
+	q := url.Values{}
+	for key, values := range query {
+		q[key] = values
+	}
+	q.Set("month", month.Format(monthLayout))
+	return PageLink{Label: month.Format("January 2006"), Path: "/calendar?" + q.Encode()}
+}
+
+// calendarMonth lays out the concerts of a month on a grid of whole weeks.
+func calendarMonth(month time.Time, concerts []Concert, today time.Time) CalendarMonth {
+	end := month.AddDate(0, 1, 0)
+	// Weekday counts from Sunday, the grid starts on Monday
+	start := month.AddDate(0, 0, -(int(month.Weekday())+6)%7)
+
+	byDay := make(map[string][]Concert)
+	for _, c := range concertsBetween(concerts, month, end) {
+		day := c.Date.Format(dayLayout)
+		byDay[day] = append(byDay[day], c)
+	}
+
+	cal := CalendarMonth{
+		Heading: month.Format("January 2006"),
+		Month:   month.Format(monthLayout),
+	}
+	for day := start; day.Before(end); {
+		week := make([]CalendarDay, 7)
+		for i := range week {
+			key := day.Format(dayLayout)
+			week[i] = CalendarDay{
+				Date:    day,
+				InMonth: day.Month() == month.Month(),
+				Today:   key == today.Format(dayLayout),
+			}
+			if week[i].InMonth {
+				week[i].Concerts = byDay[key]
+				cal.Concerts += len(byDay[key])
+			}
+			day = day.AddDate(0, 0, 1)
+		}
+		cal.Weeks = append(cal.Weeks, week)
+	}
+	return cal
+}
+
+// CalendarPage shows a month grid of the concerts of all artists, e.g. /calendar?month=2019-08.
+// It takes the artist filters of the exports and a country code or name to narrow the concerts.
+func CalendarPage(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	today := now()
+	month := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if param := query.Get("month"); param != "" {
+		var err error
+		if month, err = time.Parse(monthLayout, param); err != nil {
+			loggerFrom(r.Context()).Warn("invalid calendar month", "err", err)
+			ErrorPageDetail(w, http.StatusBadRequest, "The month must be given as yyyy-mm, e.g. /calendar?month=2019-08.")
+			return
+		}
+	}
+
+	d := currentData()
+	concerts, ok := d.filteredConcerts(w, r)
+	if !ok {
+		return
+	}
+	country := strings.TrimSpace(query.Get("country"))
+	if country != "" {
+		var matches []Concert
+		for _, c := range concerts {
+			if inCountry(c.Location, country) {
+				matches = append(matches, c)
+			}
+		}
+		concerts = matches
+	}
+
+	cal := calendarMonth(month, concerts, today)
+	cal.IDs = query.Get("ids")
+	cal.Country = country
+	cal.Prev = calendarLink(month.AddDate(0, -1, 0), query)
+	cal.Next = calendarLink(month.AddDate(0, 1, 0), query)
+
+	data := TemplateData{
+		Title:    "Concerts in " + cal.Heading,
+		Calendar: cal,
+		dataset:  d,
+	}
+	renderTemplate(w, "calendar.html", data)
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"groupie-tracker/gazetteer"
+)
+
+func TestCalendarMonth(t *testing.T) {
+	setupConcertData()
+	places = gazetteer.Default()
+	concerts, err := currentData().allConcerts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	month := time.Date(2019, time.August, 1, 0, 0, 0, 0, time.UTC)
+	cal := calendarMonth(month, concerts, time.Date(2019, time.August, 22, 20, 0, 0, 0, time.UTC))
+
+	// August 2019 starts on a Thursday and ends on a Saturday
+	if len(cal.Weeks) != 5 {
+		t.Fatalf("calendar has %d weeks, want 5", len(cal.Weeks))
+	}
+	first := cal.Weeks[0][0]
+	if first.Date.Format(dayLayout) != "2019-07-29" || first.InMonth {
+		t.Errorf("grid starts on %s (in month %v), want Monday 2019-07-29 outside the month", first.Date.Format(dayLayout), first.InMonth)
+	}
+	last := cal.Weeks[4][6]
+	if last.Date.Format(dayLayout) != "2019-09-01" || last.InMonth {
+		t.Errorf("grid ends on %s, want Sunday 2019-09-01", last.Date.Format(dayLayout))
+	}
+
+	thursday := cal.Weeks[3][3]
+	if thursday.Date.Day() != 22 || !thursday.Today || len(thursday.Concerts) != 1 || thursday.Concerts[0].Artist != "Queen" {
+		t.Errorf("22 August = %+v, want today with the Queen concert", thursday)
+	}
+	if cal.Concerts != 2 {
+		t.Errorf("calendar has %d concerts, want 2", cal.Concerts)
+	}
+}
+
+func TestCalendarPage(t *testing.T) {
+	setupConcertData()
+	places = gazetteer.Default()
+	useTemplates(t, "calendar.html")
+	now = func() time.Time { return time.Date(2020, time.July, 10, 0, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedCode   int
+		expectedTexts  []string
+		forbiddenTexts []string
+	}{
+		{"Current month", "", http.StatusOK, []string{"July 2020", "Saint Denis, France", `href="/calendar?month=2020-06"`}, nil},
+		{"Month", "?month=2019-08", http.StatusOK, []string{"August 2019", "Los Angeles, United States", "2 concerts"}, nil},
+		{"Artist filter kept in links", "?month=2021-01&ids=2", http.StatusOK, []string{"Pink Floyd", `href="/calendar?ids=2&month=2020-12"`}, nil},
+		{"Other artist", "?month=2021-01&ids=1", http.StatusOK, []string{"0 concerts"}, []string{"Pink Floyd"}},
+		{"Country code", "?month=2019-08&country=us", http.StatusOK, []string{"Los Angeles"}, nil},
+		{"Country name", "?month=2019-08&country=France", http.StatusOK, []string{"0 concerts"}, []string{"Los Angeles, United States"}},
+		{"Invalid month", "?month=08-2019", http.StatusBadRequest, nil, nil},
+		{"Invalid ids", "?ids=x", http.StatusBadRequest, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/calendar"+tt.query, nil)
+			w := httptest.NewRecorder()
+			CalendarPage(w, r)
+			if w.Code != tt.expectedCode {
+				t.Fatalf("CalendarPage() status code = %d, want %d", w.Code, tt.expectedCode)
+			}
+			body := w.Body.String()
+			for _, text := range tt.expectedTexts {
+				if !strings.Contains(body, text) {
+					t.Errorf("CalendarPage() response doesn't contain %q", text)
+				}
+			}
+			for _, text := range tt.forbiddenTexts {
+				if strings.Contains(body, text) {
+					t.Errorf("CalendarPage() response contains %q", text)
+				}
+			}
+		})
+	}
+}
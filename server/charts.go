@@ -0,0 +1,95 @@
+package server
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// ChartValue is a labelled count drawn as one bar of a chart, linking to Path when set.
+type ChartValue struct {
+	Label string
+	Path  string
+	Count int
+}
+
+// Layout of the charts in SVG user units.
+const (
+	chartWidth      = 600
+	chartLabelWidth = 190
+	chartRowHeight  = 24
+	chartHeight     = 220
+)
+
+// chartMax returns the largest count of the values, at least 1.
+func chartMax(values []ChartValue) int {
+	max := 1
+	for _, v := range values {
+		if v.Count > max {
+			max = v.Count
+		}
+	}
+	return max
+}
+
+// chartLabel writes a label, as a link when the value has a page.
+func chartLabel(b *strings.Builder, v ChartValue, x, y float64, anchor string) {
+	if v.Path != "" {
+		fmt.Fprintf(b, `<a href="%s">`, html.EscapeString(v.Path))
+	}
+	fmt.Fprintf(b, `<text x="%.1f" y="%.1f" text-anchor="%s">%s</text>`, x, y, anchor, html.EscapeString(v.Label))
+	if v.Path != "" {
+		b.WriteString("</a>")
+	}
+	b.WriteString("\n")
+}
+
+// renderBarChart draws a ranking as horizontal bars, one row per value.
+func renderBarChart(title string, values []ChartValue) string {
+	height := len(values)*chartRowHeight + 8
+	barSpace := float64(chartWidth - chartLabelWidth - 50)
+	max := chartMax(values)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" class="chart" role="img" aria-label="%s">`+"\n",
+		chartWidth, height, html.EscapeString(title))
+	b.WriteString(`<g font-size="13" fill="#f2f0ef">` + "\n")
+	for i, v := range values {
+		y := float64(i*chartRowHeight + 4)
+		width := float64(v.Count) / float64(max) * barSpace
+		chartLabel(&b, v, chartLabelWidth-8, y+16, "end")
+		fmt.Fprintf(&b, `<rect x="%d" y="%.1f" width="%.1f" height="%d" fill="#ed2100"><title>%s: %d</title></rect>`+"\n",
+			chartLabelWidth, y+2, width, chartRowHeight-6, html.EscapeString(v.Label), v.Count)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f">%d</text>`+"\n", chartLabelWidth+width+6, y+16, v.Count)
+	}
+	b.WriteString("</g>\n</svg>")
+	return b.String()
+}
+
+// renderColumnChart draws a distribution as vertical columns, in the order of the values.
+func renderColumnChart(title string, values []ChartValue) string {
+	const top, bottom = 20, 30
+	plot := float64(chartHeight - top - bottom)
+	max := chartMax(values)
+	slot := float64(chartWidth)
+	if len(values) > 0 {
+		slot /= float64(len(values))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" class="chart" role="img" aria-label="%s">`+"\n",
+		chartWidth, chartHeight, html.EscapeString(title))
+	fmt.Fprintf(&b, `<line x1="0" y1="%d" x2="%d" y2="%d" stroke="#5c5652"/>`+"\n", chartHeight-bottom, chartWidth, chartHeight-bottom)
+	b.WriteString(`<g font-size="13" fill="#f2f0ef">` + "\n")
+	for i, v := range values {
+		x := float64(i) * slot
+		h := float64(v.Count) / float64(max) * plot
+		y := float64(chartHeight-bottom) - h
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="#ed2100"><title>%s: %d</title></rect>`+"\n",
+			x+slot*0.15, y, slot*0.7, h, html.EscapeString(v.Label), v.Count)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle">%d</text>`+"\n", x+slot/2, y-5, v.Count)
+		chartLabel(&b, v, x+slot/2, chartHeight-bottom+18, "middle")
+	}
+	b.WriteString("</g>\n</svg>")
+	return b.String()
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"groupie-tracker/analytics"
+	"groupie-tracker/gazetteer"
+)
+
+// Number of artists the comparison page shows side by side.
+const (
+	minCompared = 2
+	maxCompared = 4
+)
+
+// ComparedConcert is a concert of a compared artist, marked when another compared
+// artist played the same city or on the same day.
+type ComparedConcert struct {
+	Concert
+	SharedCity bool
+	SharedDate bool
+}
+
+// ComparedArtist is one column of the comparison page.
+type ComparedArtist struct {
+	Artist    Artist
+	Concerts  []ComparedConcert
+	Cities    int
+	Analytics analytics.Summary
+}
+
+// SharedCity is a concert location played by several compared artists.
+type SharedCity struct {
+	Slug    string
+	Name    string
+	Artists []Artist
+}
+
+// Comparison holds the artists of the comparison page and what their tours have in common.
+type Comparison struct {
+	Artists      []ComparedArtist
+	SharedCities []SharedCity
+	SharedDates  []ConcertGroup // days on which several compared artists played
+}
+
+// parseCompareIDs reads the comma-separated artist IDs to compare, keeping their order.
+func (d *dataset) parseCompareIDs(list string) ([]Artist, *requestError) {
+	usage := fmt.Sprintf("Choose %d to %d artists to compare, e.g. /compare?ids=1,2.", minCompared, maxCompared)
+	if strings.TrimSpace(list) == "" {
+		return nil, &requestError{http.StatusBadRequest, usage}
+	}
+
+	var compared []Artist
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(list, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, &requestError{http.StatusBadRequest, "Artist IDs must be numbers separated by commas. " + usage}
+		}
+		if seen[id] {
+			return nil, &requestError{http.StatusBadRequest, fmt.Sprintf("Artist %d is listed more than once. %s", id, usage)}
+		}
+		seen[id] = true
+		artist, ok := d.artistByID(id)
+		if !ok {
+			return nil, &requestError{http.StatusNotFound, fmt.Sprintf("There is no artist with ID %d.", id)}
+		}
+		compared = append(compared, artist)
+	}
+
+	if len(compared) < minCompared || len(compared) > maxCompared {
+		return nil, &requestError{http.StatusBadRequest, usage}
+	}
+	return compared, nil
+}
+
+// compareArtists builds the comparison of artists from their concerts.
+func compareArtists(compared []Artist, concerts [][]Concert) Comparison {
+	// Artists who played each city and each day
+	cityArtists := make(map[string]map[int]bool)
+	dayArtists := make(map[string]map[int]bool)
+	for _, list := range concerts {
+		for _, c := range list {
+			city, day := gazetteer.Normalize(c.Location), c.Date.Format(dayLayout)
+			if cityArtists[city] == nil {
+				cityArtists[city] = make(map[int]bool)
+			}
+			if dayArtists[day] == nil {
+				dayArtists[day] = make(map[int]bool)
+			}
+			cityArtists[city][c.ArtistID] = true
+			dayArtists[day][c.ArtistID] = true
+		}
+	}
+
+	var comparison Comparison
+	var sharedDays []Concert
+	for i, artist := range compared {
+		column := ComparedArtist{Artist: artist, Analytics: tourAnalytics(concerts[i])}
+		cities := make(map[string]bool)
+		for _, c := range concerts[i] {
+			city, day := gazetteer.Normalize(c.Location), c.Date.Format(dayLayout)
+			cities[city] = true
+			shared := ComparedConcert{Concert: c, SharedCity: len(cityArtists[city]) > 1, SharedDate: len(dayArtists[day]) > 1}
+			if shared.SharedDate {
+				sharedDays = append(sharedDays, c)
+			}
+			column.Concerts = append(column.Concerts, shared)
+		}
+		column.Cities = len(cities)
+		comparison.Artists = append(comparison.Artists, column)
+	}
+
+	for slug, ids := range cityArtists {
+		if len(ids) < 2 {
+			continue
+		}
+		city := SharedCity{Slug: slug, Name: locationName(slug)}
+		for _, artist := range compared {
+			if ids[artist.ID] {
+				city.Artists = append(city.Artists, artist)
+			}
+		}
+		comparison.SharedCities = append(comparison.SharedCities, city)
+	}
+	sort.Slice(comparison.SharedCities, func(i, j int) bool {
+		return comparison.SharedCities[i].Name < comparison.SharedCities[j].Name
+	})
+
+	sortConcerts(sharedDays)
+	comparison.SharedDates = groupConcerts(sharedDays, byDay)
+	return comparison
+}
+
+// ComparePage shows two to four artists given by ?ids=1,7,12 side by side.
+func ComparePage(w http.ResponseWriter, r *http.Request) {
+	d := currentData()
+	compared, bad := d.parseCompareIDs(r.URL.Query().Get("ids"))
+	if bad != nil {
+		loggerFrom(r.Context()).Warn("invalid comparison", "detail", bad.detail)
+		ErrorPageDetail(w, bad.code, bad.detail)
+		return
+	}
+
+	concerts := make([][]Concert, len(compared))
+	for i, artist := range compared {
+		rel, err := d.relationFor(r.Context(), artist)
+		if err != nil {
+			loggerFrom(r.Context()).Error("could not load the concerts", "artist", artist.Name, "err", err)
+			ErrorPage(w, http.StatusInternalServerError)
+			return
+		}
+		concerts[i] = concertsOf(artist, rel)
+	}
+
+	names := make([]string, len(compared))
+	for i, artist := range compared {
+		names[i] = artist.Name
+	}
+	data := TemplateData{
+		Title:      "Compare " + strings.Join(names, ", "),
+		Comparison: compareArtists(compared, concerts),
+		dataset:    d,
+	}
+	renderTemplate(w, "compare.html", data)
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"groupie-tracker/gazetteer"
+)
+
+func TestParseCompareIDs(t *testing.T) {
+	list, rels := concertData()
+	for id := 3; id <= 5; id++ {
+		list = append(list, Artist{ID: id})
+	}
+	d := useData(list, rels)
+
+	tests := []struct {
+		name           string
+		ids            string
+		expectedCode   int
+		expectedDetail string
+	}{
+		{"Two artists", "2,1", 0, ""},
+		{"Missing", "", http.StatusBadRequest, "Choose 2 to 4 artists"},
+		{"Only one", "1", http.StatusBadRequest, "Choose 2 to 4 artists"},
+		{"Too many", "1,2,3,4,5", http.StatusBadRequest, "Choose 2 to 4 artists"},
+		{"Not a number", "1,two", http.StatusBadRequest, "must be numbers"},
+		{"Duplicate", "1, 1", http.StatusBadRequest, "Artist 1 is listed more than once"},
+		{"Unknown artist", "1,42", http.StatusNotFound, "no artist with ID 42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compared, bad := d.parseCompareIDs(tt.ids)
+			if tt.expectedCode == 0 {
+				if bad != nil {
+					t.Fatalf("parseCompareIDs(%q) error = %s", tt.ids, bad.detail)
+				}
+				if compared[0].Name != "Pink Floyd" || compared[1].Name != "Queen" {
+					t.Errorf("parseCompareIDs(%q) does not keep the order of the IDs", tt.ids)
+				}
+				return
+			}
+			if bad == nil {
+				t.Fatalf("parseCompareIDs(%q) accepted an invalid list", tt.ids)
+			}
+			if bad.code != tt.expectedCode || !strings.Contains(bad.detail, tt.expectedDetail) {
+				t.Errorf("parseCompareIDs(%q) = %d %q, want %d containing %q", tt.ids, bad.code, bad.detail, tt.expectedCode, tt.expectedDetail)
+			}
+		})
+	}
+}
+
+func TestComparePage(t *testing.T) {
+	places = gazetteer.Default()
+	useTemplates(t, "compare.html")
+	// Pink Floyd plays Los Angeles on the second of Queen's two nights there
+	list, rels := concertData()
+	rels[2].DatesLocation["los_angeles-usa"] = []string{"23-08-2019"}
+	useData(list, rels)
+
+	r := httptest.NewRequest(http.MethodGet, "/compare?ids=1,2", nil)
+	w := httptest.NewRecorder()
+	ComparePage(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("ComparePage() status code = %d, want %d", w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	expected := []string{"Queen", "Pink Floyd", "Freddie Mercury, Brian May", "Cities in common", `href="/locations/los_angeles-usa"`, "Shared tour dates", `href="/dates/2019-08-23"`}
+	for _, text := range expected {
+		if !strings.Contains(body, text) {
+			t.Errorf("ComparePage() response doesn't contain %q", text)
+		}
+	}
+	if strings.Contains(body, `href="/dates/2019-08-22"`) {
+		t.Errorf("ComparePage() shares a date only Queen played")
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/compare?ids=1", nil)
+	w = httptest.NewRecorder()
+	ComparePage(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("ComparePage() with one artist status code = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestCompareArtists(t *testing.T) {
+	places = gazetteer.Default()
+	list, rels := concertData()
+	rels[2].DatesLocation["Los_Angeles-USA"] = []string{"01-01-2000"}
+	useData(list, rels)
+
+	concerts := [][]Concert{concertsOf(list[0], rels[1]), concertsOf(list[1], rels[2])}
+	comparison := compareArtists(list, concerts)
+
+	if len(comparison.SharedCities) != 1 || len(comparison.SharedCities[0].Artists) != 2 {
+		t.Fatalf("shared cities = %+v, want Los Angeles played by both", comparison.SharedCities)
+	}
+	if len(comparison.SharedDates) != 0 {
+		t.Errorf("shared dates = %+v, want none", comparison.SharedDates)
+	}
+	queen := comparison.Artists[0]
+	if queen.Cities != 2 || !queen.Concerts[0].SharedCity || queen.Concerts[2].SharedCity {
+		t.Errorf("Queen concerts = %+v, want Los Angeles marked as shared", queen.Concerts)
+	}
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"groupie-tracker/analytics"
+)
+
+// dateLayout is the day-month-year format used by the upstream API.
+const dateLayout = "02-01-2006"
+
+// Concert is a single show played by an artist at one location on one day.
+type Concert struct {
+	ArtistID int
+	Artist   string
+	Location string // upstream location slug, e.g. "los_angeles-usa"
+	Date     time.Time
+}
+
+// now returns the current time; tests replace it to get reproducible pages.
+var now = time.Now
+
+// LocationName returns the display name of the concert location.
+func (c Concert) LocationName() string {
+	return locationName(c.Location)
+}
+
+// Day returns the concert date in the upstream day-month-year format.
+func (c Concert) Day() string {
+	return c.Date.Format(dateLayout)
+}
+
+// parseDate parses an upstream date such as "23-08-2019".
+// The leading asterisk the dates endpoint puts on some entries is ignored.
+func parseDate(s string) (time.Time, error) {
+	return time.Parse(dateLayout, strings.TrimPrefix(strings.TrimSpace(s), "*"))
+}
+
+// formatLocation turns an upstream slug like "los_angeles-usa" into "Los Angeles, USA".
+func formatLocation(slug string) string {
+	city, country, found := strings.Cut(slug, "-")
+	if !found {
+		return titleWords(city)
+	}
+	return titleWords(city) + ", " + formatCountry(country)
+}
+
+// formatCountry capitalizes a country slug, keeping abbreviations in upper case.
+func formatCountry(slug string) string {
+	if len(slug) <= 3 {
+		return strings.ToUpper(slug)
+	}
+	return titleWords(slug)
+}
+
+// titleWords replaces underscores with spaces and capitalizes every word.
+func titleWords(s string) string {
+	words := strings.Fields(strings.ReplaceAll(s, "_", " "))
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// concertsOf flattens the relation of an artist into concerts sorted by date.
+// Dates that cannot be parsed are logged and skipped.
+func concertsOf(artist Artist, rel Relation) []Concert {
+	var concerts []Concert
+	for location, dates := range rel.DatesLocation {
+		for _, d := range dates {
+			date, err := parseDate(d)
+			if err != nil {
+				slog.Warn("skipping a concert", "artist", artist.Name, "err", err)
+				continue
+			}
+			concerts = append(concerts, Concert{
+				ArtistID: artist.ID,
+				Artist:   artist.Name,
+				Location: location,
+				Date:     date,
+			})
+		}
+	}
+	sortConcerts(concerts)
+	return concerts
+}
+
+// sortConcerts orders concerts chronologically, breaking ties by location and artist.
+func sortConcerts(concerts []Concert) {
+	sort.Slice(concerts, func(i, j int) bool {
+		a, b := concerts[i], concerts[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		if a.Location != b.Location {
+			return a.Location < b.Location
+		}
+		return a.ArtistID < b.ArtistID
+	})
+}
+
+// tourAnalytics computes the tour analytics of concerts, resolving their locations with the gazetteer.
+func tourAnalytics(concerts []Concert) analytics.Summary {
+	stops := make([]analytics.Stop, len(concerts))
+	for i, c := range concerts {
+		place, ok := places.Lookup(c.Location)
+		stops[i] = analytics.Stop{Date: c.Date, Location: c.Location, Place: place, Resolved: ok}
+	}
+	return analytics.Summarize(stops)
+}
+
+// allConcerts returns the concerts of every artist sorted by date.
+func (d *dataset) allConcerts(ctx context.Context) ([]Concert, error) {
+	var concerts []Concert
+	for _, artist := range d.artists {
+		rel, err := d.relationFor(ctx, artist)
+		if err != nil {
+			return nil, err
+		}
+		concerts = append(concerts, concertsOf(artist, rel)...)
+	}
+	sortConcerts(concerts)
+	return concerts, nil
+}
+
+// relationFor returns the relation of an artist, preferring the preloaded relations
+// and falling back to the artist's own relation URL.
+func (d *dataset) relationFor(ctx context.Context, artist Artist) (Relation, error) {
+	if rel, ok := d.relations[artist.ID]; ok {
+		return rel, nil
+	}
+	return FetchRelation(ctx, artist.Relations)
+}
+
+// artistFromParam looks up an artist by the slug or the ID given in a URL parameter.
+// The slug may be written differently, e.g. "Queen" for "queen".
+func (d *dataset) artistFromParam(param string) (Artist, error) {
+	if artist, ok := d.bySlug[param]; ok {
+		return artist, nil
+	}
+	if artist, ok := d.bySlug[memberSlug(param)]; ok {
+		return artist, nil
+	}
+	id, err := strconv.Atoi(param)
+	if err != nil {
+		return Artist{}, fmt.Errorf("no artist %q", param)
+	}
+	artist, ok := d.artistByID(id)
+	if !ok {
+		return Artist{}, fmt.Errorf("artist %d does not exist", id)
+	}
+	return artist, nil
+}
+
+// artistByID returns the artist with the given ID; IDs are positions in the artists list, starting at 1.
+func (d *dataset) artistByID(id int) (Artist, bool) {
+	if id <= 0 || id > len(d.artists) {
+		return Artist{}, false
+	}
+	return d.artists[id-1], true
+}
+
+// artistConcerts returns the artist named by the "artist" path value and its concerts.
+// It renders an error page and reports false when the artist cannot be resolved.
+func artistConcerts(w http.ResponseWriter, r *http.Request) (Artist, []Concert, bool) {
+	d := currentData()
+	artist, err := d.artistFromParam(r.PathValue("artist"))
+	if err != nil {
+		loggerFrom(r.Context()).Warn("unknown artist", "err", err)
+		ErrorPage(w, http.StatusNotFound)
+		return Artist{}, nil, false
+	}
+
+	rel, err := d.relationFor(r.Context(), artist)
+	if err != nil {
+		loggerFrom(r.Context()).Error("could not load the concerts", "artist", artist.Name, "err", err)
+		ErrorPage(w, http.StatusInternalServerError)
+		return Artist{}, nil, false
+	}
+	return artist, concertsOf(artist, rel), true
+}
+
+// filteredConcerts returns the concerts of the artists selected by the filter parameters
+// of the request, sorted by date. It renders an error page and reports false on failure.
+func (d *dataset) filteredConcerts(w http.ResponseWriter, r *http.Request) ([]Concert, bool) {
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		loggerFrom(r.Context()).Warn("invalid filter", "err", err)
+		ErrorPage(w, http.StatusBadRequest)
+		return nil, false
+	}
+
+	matched, rels, err := d.filterArtists(r.Context(), filter)
+	if err != nil {
+		loggerFrom(r.Context()).Error("could not filter the artists", "err", err)
+		ErrorPage(w, http.StatusInternalServerError)
+		return nil, false
+	}
+
+	var concerts []Concert
+	for _, artist := range matched {
+		concerts = append(concerts, concertsOf(artist, rels[artist.ID])...)
+	}
+	sortConcerts(concerts)
+	return concerts, true
+}
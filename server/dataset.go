@@ -0,0 +1,86 @@
+package server
+
+import (
+	"strconv"
+	"sync/atomic"
+)
+
+// dataset holds the artists, their concerts and the data derived from them. It is
+// never modified once published: a refresh publishes a new one, so that a request
+// keeps a consistent view by reading currentData once.
+type dataset struct {
+	artists []Artist
+	// relations holds the concerts of every artist keyed by artist ID
+	relations map[int]Relation
+	stats     DatasetStats
+	// slugs and bySlug map the artist IDs to the names used in URLs and back
+	slugs  map[int]string
+	bySlug map[string]Artist
+}
+
+// current is the dataset served, nil until the data is loaded.
+var current atomic.Pointer[dataset]
+
+// currentData returns the dataset served, an empty one before the data is loaded.
+func currentData() *dataset {
+	if d := current.Load(); d != nil {
+		return d
+	}
+	return &dataset{}
+}
+
+// newDataset computes the data derived from the artists and their relations. It
+// makes no upstream requests: an artist without a relation has no concerts.
+func newDataset(list []Artist, rels map[int]Relation) *dataset {
+	d := &dataset{artists: list, relations: rels}
+	d.slugs, d.bySlug = artistSlugs(list)
+	var concerts []Concert
+	for _, artist := range list {
+		concerts = append(concerts, concertsOf(artist, rels[artist.ID])...)
+	}
+	sortConcerts(concerts)
+	d.stats = d.computeStats(concerts)
+	return d
+}
+
+// artistSlugs gives every artist the name used in URLs, e.g. "pink-floyd". Artists
+// whose names give the same slug are told apart by their ID, e.g. "pink-floyd-7".
+func artistSlugs(list []Artist) (map[int]string, map[string]Artist) {
+	names := make(map[string]int, len(list))
+	for _, artist := range list {
+		names[memberSlug(artist.Name)]++
+	}
+	slugs := make(map[int]string, len(list))
+	bySlug := make(map[string]Artist, len(list))
+	for _, artist := range list {
+		slug := memberSlug(artist.Name)
+		switch {
+		case slug == "":
+			slug = strconv.Itoa(artist.ID)
+		case names[slug] > 1:
+			slug += "-" + strconv.Itoa(artist.ID)
+		}
+		slugs[artist.ID] = slug
+		bySlug[slug] = artist
+	}
+	return slugs, bySlug
+}
+
+// slug returns the name of the artist as used in URLs.
+func (d *dataset) slug(a Artist) string {
+	if slug, ok := d.slugs[a.ID]; ok {
+		return slug
+	}
+	if slug := memberSlug(a.Name); slug != "" {
+		return slug
+	}
+	return strconv.Itoa(a.ID)
+}
+
+// artistPath returns the URL of the page of the artist with the given ID.
+func (d *dataset) artistPath(id int) string {
+	if artist, ok := d.artistByID(id); ok {
+		return "/artists/" + d.slug(artist)
+	}
+	return "/artists/" + strconv.Itoa(id)
+}
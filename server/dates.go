@@ -0,0 +1,192 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Layouts of the periods accepted in /dates/ URLs.
+const (
+	yearLayout  = "2006"
+	monthLayout = "2006-01"
+	dayLayout   = "2006-01-02"
+)
+
+// PageLink is a labelled link to another page.
+type PageLink struct {
+	Label string
+	Path  string
+}
+
+// ConcertGroup is a titled list of concerts, such as the concerts of one month.
+type ConcertGroup struct {
+	Label    string
+	Path     string
+	Concerts []Concert
+}
+
+// DateDetails describes the concerts of a year, month or day.
+type DateDetails struct {
+	Heading   string
+	Concerts  int
+	Prev      PageLink
+	Next      PageLink
+	Up        PageLink
+	Groups    []ConcertGroup
+	OnThisDay []ConcertGroup // concerts on the same day of the year in other years
+	Years     []ConcertGroup // years with concerts, on the index page only
+}
+
+// groupConcerts groups sorted concerts by the key and link returned for each concert.
+func groupConcerts(concerts []Concert, key func(Concert) (label, path string)) []ConcertGroup {
+	var groups []ConcertGroup
+	for _, c := range concerts {
+		label, path := key(c)
+		if len(groups) == 0 || groups[len(groups)-1].Label != label {
+			groups = append(groups, ConcertGroup{Label: label, Path: path})
+		}
+		groups[len(groups)-1].Concerts = append(groups[len(groups)-1].Concerts, c)
+	}
+	return groups
+}
+
+func byYear(c Concert) (string, string) {
+	return c.Date.Format(yearLayout), "/dates/" + c.Date.Format(yearLayout)
+}
+
+func byMonth(c Concert) (string, string) {
+	return c.Date.Format("January 2006"), "/dates/" + c.Date.Format(monthLayout)
+}
+
+func byDay(c Concert) (string, string) {
+	return c.Date.Format("Monday 2 January 2006"), "/dates/" + c.Date.Format(dayLayout)
+}
+
+// onThisDay returns the concerts played on the day and month of date in any other year, grouped by year.
+func onThisDay(concerts []Concert, date time.Time) []ConcertGroup {
+	var matches []Concert
+	for _, c := range concerts {
+		if c.Date.Month() == date.Month() && c.Date.Day() == date.Day() && c.Date.Year() != date.Year() {
+			matches = append(matches, c)
+		}
+	}
+	return groupConcerts(matches, byYear)
+}
+
+// concertsBetween returns the sorted concerts in [from, to).
+func concertsBetween(concerts []Concert, from, to time.Time) []Concert {
+	var matches []Concert
+	for _, c := range concerts {
+		if !c.Date.Before(from) && c.Date.Before(to) {
+			matches = append(matches, c)
+		}
+	}
+	return matches
+}
+
+// dateDetails builds the page of the period named in a /dates/ URL: a year, a month or a day.
+func dateDetails(period string, concerts []Concert) (DateDetails, error) {
+	var details DateDetails
+	if t, err := time.Parse(yearLayout, period); err == nil {
+		end := t.AddDate(1, 0, 0)
+		prev := t.AddDate(-1, 0, 0)
+		matches := concertsBetween(concerts, t, end)
+		details = DateDetails{
+			Heading: t.Format("2006"),
+			Prev:    PageLink{prev.Format(yearLayout), "/dates/" + prev.Format(yearLayout)},
+			Next:    PageLink{end.Format(yearLayout), "/dates/" + end.Format(yearLayout)},
+			Up:      PageLink{"All dates", "/dates/"},
+			Groups:  groupConcerts(matches, byMonth),
+		}
+		details.Concerts = len(matches)
+		return details, nil
+	}
+	if t, err := time.Parse(monthLayout, period); err == nil {
+		end := t.AddDate(0, 1, 0)
+		prev := t.AddDate(0, -1, 0)
+		matches := concertsBetween(concerts, t, end)
+		details = DateDetails{
+			Heading: t.Format("January 2006"),
+			Prev:    PageLink{prev.Format("January 2006"), "/dates/" + prev.Format(monthLayout)},
+			Next:    PageLink{end.Format("January 2006"), "/dates/" + end.Format(monthLayout)},
+			Up:      PageLink{t.Format(yearLayout), "/dates/" + t.Format(yearLayout)},
+			Groups:  groupConcerts(matches, byDay),
+		}
+		details.Concerts = len(matches)
+		return details, nil
+	}
+	if t, err := time.Parse(dayLayout, period); err == nil {
+		end := t.AddDate(0, 0, 1)
+		prev := t.AddDate(0, 0, -1)
+		matches := concertsBetween(concerts, t, end)
+		details = DateDetails{
+			Heading:   t.Format("Monday 2 January 2006"),
+			Prev:      PageLink{prev.Format("2 January 2006"), "/dates/" + prev.Format(dayLayout)},
+			Next:      PageLink{end.Format("2 January 2006"), "/dates/" + end.Format(dayLayout)},
+			Up:        PageLink{t.Format("January 2006"), "/dates/" + t.Format(monthLayout)},
+			Groups:    groupConcerts(matches, byDay),
+			OnThisDay: onThisDay(concerts, t),
+		}
+		details.Concerts = len(matches)
+		return details, nil
+	}
+	return details, fmt.Errorf("invalid period %q, want yyyy, yyyy-mm or yyyy-mm-dd", period)
+}
+
+// DatesPage shows the concerts played on the current calendar day in history and links to every year.
+func DatesPage(w http.ResponseWriter, r *http.Request) {
+	d := currentData()
+	concerts, err := d.allConcerts(r.Context())
+	if err != nil {
+		loggerFrom(r.Context()).Error("could not list the concerts", "err", err)
+		ErrorPage(w, http.StatusInternalServerError)
+		return
+	}
+
+	today := now()
+	details := DateDetails{
+		Heading:   "On this day, " + today.Format("2 January"),
+		Up:        PageLink{today.Format("January 2006"), "/dates/" + today.Format(monthLayout)},
+		OnThisDay: onThisDay(concerts, today),
+		Years:     groupConcerts(concerts, byYear),
+		Concerts:  len(concerts),
+	}
+	// Most recent years first in the history
+	sort.SliceStable(details.OnThisDay, func(i, j int) bool {
+		return details.OnThisDay[i].Label > details.OnThisDay[j].Label
+	})
+
+	data := TemplateData{
+		Title:   "Concert Dates",
+		Date:    details,
+		dataset: d,
+	}
+	renderTemplate(w, "dates.html", data)
+}
+
+// DatePage lists the concerts of all artists in a year, month or day given as yyyy, yyyy-mm or yyyy-mm-dd.
+func DatePage(w http.ResponseWriter, r *http.Request) {
+	d := currentData()
+	concerts, err := d.allConcerts(r.Context())
+	if err != nil {
+		loggerFrom(r.Context()).Error("could not list the concerts", "err", err)
+		ErrorPage(w, http.StatusInternalServerError)
+		return
+	}
+
+	details, err := dateDetails(r.PathValue("date"), concerts)
+	if err != nil {
+		loggerFrom(r.Context()).Warn("invalid date", "err", err)
+		ErrorPage(w, http.StatusBadRequest)
+		return
+	}
+
+	data := TemplateData{
+		Title:   "Concerts in " + details.Heading,
+		Date:    details,
+		dataset: d,
+	}
+	renderTemplate(w, "dates.html", data)
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"groupie-tracker/gazetteer"
+)
+
+func TestDatesPage(t *testing.T) {
+	setupConcertData()
+	places = gazetteer.Default()
+	useTemplates(t, "dates.html")
+	now = func() time.Time { return time.Date(2024, time.August, 23, 12, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	r := httptest.NewRequest(http.MethodGet, "/dates/", nil)
+	w := httptest.NewRecorder()
+	DatesPage(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("DatesPage() status code = %d, want %d", w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	expected := []string{"On this day, 23 August", `href="/dates/2019"`, "Los Angeles, United States", `href="/dates/2020"`, `href="/dates/2021"`}
+	for _, text := range expected {
+		if !strings.Contains(body, text) {
+			t.Errorf("DatesPage() response doesn't contain %q", text)
+		}
+	}
+	if strings.Contains(body, "Pink Floyd") {
+		t.Errorf("DatesPage() lists a concert that was not played on this day")
+	}
+}
+
+func TestDatePage(t *testing.T) {
+	setupConcertData()
+	places = gazetteer.Default()
+	useTemplates(t, "dates.html")
+
+	tests := []struct {
+		name          string
+		period        string
+		expectedCode  int
+		expectedTexts []string
+	}{
+		{"Year", "2019", http.StatusOK, []string{"August 2019", `href="/dates/2019-08"`, "22-08-2019", "23-08-2019", `href="/dates/2018"`, `href="/dates/2020"`}},
+		{"Month", "2019-08", http.StatusOK, []string{"Thursday 22 August 2019", "Friday 23 August 2019", `href="/dates/2019-07"`, `href="/dates/2019-09"`}},
+		{"Day", "2019-08-22", http.StatusOK, []string{"Thursday 22 August 2019", "Queen", `href="/dates/2019-08-21"`, `href="/dates/2019-08-23"`}},
+		{"Empty year", "2000", http.StatusOK, []string{"No concerts in 2000."}},
+		{"Invalid month", "2019-13", http.StatusBadRequest, nil},
+		{"Invalid format", "22-08-2019", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/dates/"+tt.period, nil)
+			r.SetPathValue("date", tt.period)
+			w := httptest.NewRecorder()
+			DatePage(w, r)
+			if w.Code != tt.expectedCode {
+				t.Fatalf("DatePage() status code = %d, want %d", w.Code, tt.expectedCode)
+			}
+			body := w.Body.String()
+			for _, text := range tt.expectedTexts {
+				if !strings.Contains(body, text) {
+					t.Errorf("DatePage() response doesn't contain %q", text)
+				}
+			}
+		})
+	}
+}
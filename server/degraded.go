@@ -0,0 +1,79 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Delays between the attempts to load the data after a failure: the first retry
+// waits retryMin, each next one twice as long, up to retryMax.
+var (
+	retryMin = 5 * time.Second
+	retryMax = 5 * time.Minute
+)
+
+// maintenanceDetail is shown on the pages requested before any data was loaded.
+const maintenanceDetail = "The concert data could not be loaded yet. It is being retried, so please come back in a moment."
+
+var (
+	// dataLoaded is set once artists were loaded, from the upstream API or a snapshot.
+	dataLoaded atomic.Bool
+	// upstreamLoaded is set once artists were loaded from the upstream API; until then,
+	// even with a snapshot served, the refresher retries at the failure delays.
+	upstreamLoaded atomic.Bool
+	// nextAttempt is the Unix time in nanoseconds of the next data load, zero when none is planned.
+	nextAttempt atomic.Int64
+)
+
+// DataLoaded reports whether artists were loaded, from the upstream API or a snapshot.
+func DataLoaded() bool {
+	return dataLoaded.Load()
+}
+
+// retryAfter returns the seconds until the next attempt to load the data, at least one.
+func retryAfter() int {
+	next := nextAttempt.Load()
+	if next == 0 {
+		return int(retryMin.Seconds())
+	}
+	seconds := math.Ceil(time.Until(time.Unix(0, next)).Seconds())
+	return int(math.Max(1, seconds))
+}
+
+// degradedExempt reports whether a path works without data: health checks,
+// metrics and the static files the maintenance page uses.
+func degradedExempt(path string) bool {
+	switch path {
+	case "/healthz", "/readyz", "/metrics":
+		return true
+	}
+	return strings.HasPrefix(path, "/static/")
+}
+
+// Degraded answers with a 503 maintenance page and a Retry-After header until
+// the data is loaded, so that the server can start while the upstream API is down.
+// These responses never reach the routes, so they are counted as the "degraded" route.
+func Degraded(next http.Handler) http.Handler {
+	maintenance := instrument("degraded", serveMaintenance)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if DataLoaded() || degradedExempt(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		maintenance(w, r)
+	})
+}
+
+// serveMaintenance answers that the data is not loaded yet, as JSON for the API.
+func serveMaintenance(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter()))
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		apiError(w, http.StatusServiceUnavailable)
+		return
+	}
+	ErrorPageDetail(w, http.StatusServiceUnavailable, maintenanceDetail)
+}
@@ -0,0 +1,239 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// useNoData starts the test without any data loaded and restores the previous state afterwards.
+func useNoData(t *testing.T) {
+	t.Helper()
+	oldLoaded, oldUpstream, oldData := dataLoaded.Load(), upstreamLoaded.Load(), current.Load()
+	t.Cleanup(func() {
+		dataLoaded.Store(oldLoaded)
+		upstreamLoaded.Store(oldUpstream)
+		current.Store(oldData)
+		nextAttempt.Store(0)
+	})
+	dataLoaded.Store(false)
+	upstreamLoaded.Store(false)
+	current.Store(nil)
+}
+
+func TestDegraded(t *testing.T) {
+	useNoData(t)
+	useTemplates(t, "errors.html")
+	handler := Degraded(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("served"))
+	}))
+
+	tests := []struct {
+		name         string
+		path         string
+		expectedCode int
+		contains     string
+	}{
+		{"Page", "/artists/queen", http.StatusServiceUnavailable, "being retried"},
+		{"API", "/api/artists", http.StatusServiceUnavailable, `"status": 503`},
+		{"Health check", "/healthz", http.StatusOK, "served"},
+		{"Readiness", "/readyz", http.StatusOK, "served"},
+		{"Metrics", "/metrics", http.StatusOK, "served"},
+		{"Static file", "/static/style.css", http.StatusOK, "served"},
+	}
+	nextAttempt.Store(time.Now().Add(10 * time.Second).UnixNano())
+	unavailable := httpRequests.Value("degraded", "503")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.expectedCode || !strings.Contains(w.Body.String(), tt.contains) {
+				t.Errorf("Degraded() = %d %q, want %d with %q", w.Code, w.Body.String(), tt.expectedCode, tt.contains)
+			}
+			if tt.expectedCode != http.StatusServiceUnavailable {
+				return
+			}
+			retry, err := strconv.Atoi(w.Header().Get("Retry-After"))
+			if err != nil || retry < 9 || retry > 10 {
+				t.Errorf("Retry-After = %q, want the seconds until the next attempt", w.Header().Get("Retry-After"))
+			}
+		})
+	}
+
+	// The maintenance responses show in the metrics, which the routes never see
+	if got := httpRequests.Value("degraded", "503") - unavailable; got != 2 {
+		t.Errorf("maintenance responses counted %v times, want 2", got)
+	}
+
+	dataLoaded.Store(true)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/artists/queen", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Degraded() with data = %d, want the page", w.Code)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	useNoData(t)
+	if got := retryAfter(); got != int(retryMin.Seconds()) {
+		t.Errorf("retryAfter() without a planned attempt = %d, want %v", got, retryMin.Seconds())
+	}
+	nextAttempt.Store(time.Now().Add(-time.Minute).UnixNano())
+	if got := retryAfter(); got != 1 {
+		t.Errorf("retryAfter() for a late attempt = %d, want 1", got)
+	}
+}
+
+func TestLoad_Degraded(t *testing.T) {
+	resetStatus(t)
+	useNoData(t)
+	upstream := newFakeUpstream(t)
+	useUpstream(t, upstream)
+	useSnapshot(t)
+	oldOverrides := gazetteerOverrides
+	gazetteerOverrides = ""
+	defer func() { gazetteerOverrides = oldOverrides }()
+
+	// Without the upstream API nor a snapshot the server starts without data
+	artistsURL = "http://127.0.0.1:1/artists"
+	if err := Load(); err != nil {
+		t.Fatalf("Load() without the upstream API = %v, want the degraded mode", err)
+	}
+	if DataLoaded() {
+		t.Fatal("DataLoaded() = true without any data")
+	}
+
+	// A snapshot saved earlier is served instead
+	artistsURL = upstream.URL + "/artists"
+	if err := LoadData(); err != nil {
+		t.Fatal(err)
+	}
+	dataLoaded.Store(false)
+	current.Store(nil)
+	artistsURL = "http://127.0.0.1:1/artists"
+	if err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(currentData().artists); !DataLoaded() || n != 1 {
+		t.Errorf("Load() with a snapshot loaded %d artists, want the one of the snapshot", n)
+	}
+}
+
+func TestRunRefresher_Retry(t *testing.T) {
+	useNoData(t)
+	upstream := newFakeUpstream(t)
+	useUpstream(t, upstream)
+	useSnapshot(t)
+	oldMin, oldMax := retryMin, retryMax
+	defer func() { retryMin, retryMax = oldMin, oldMax }()
+	retryMin, retryMax = time.Millisecond, 4*time.Millisecond
+
+	// The upstream API fails a few times before answering
+	var failures atomic.Int32
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures.Add(1) <= 4 {
+			http.Error(w, "down", http.StatusBadGateway)
+			return
+		}
+		upstream.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer flaky.Close()
+	artistsURL, relationsURL = flaky.URL+"/artists", flaky.URL+"/relation"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// Without an interval the refresher stops once the data is loaded
+	RunRefresher(ctx, 0)
+	if ctx.Err() != nil {
+		t.Fatal("RunRefresher() did not stop after loading the data")
+	}
+	if n := len(currentData().artists); !DataLoaded() || n != 1 {
+		t.Errorf("RunRefresher() loaded %d artists, want the upstream one", n)
+	}
+	if nextAttempt.Load() != 0 {
+		t.Error("RunRefresher() left an attempt planned after stopping")
+	}
+}
+
+func TestRunRefresher_FromSnapshot(t *testing.T) {
+	resetStatus(t)
+	useNoData(t)
+	upstream := newFakeUpstream(t)
+	useUpstream(t, upstream)
+	useSnapshot(t)
+	oldMin, oldMax := retryMin, retryMax
+	defer func() { retryMin, retryMax = oldMin, oldMax }()
+	retryMin, retryMax = time.Millisecond, 4*time.Millisecond
+
+	// The upstream API is down at startup, so a snapshot is served
+	var failures atomic.Int32
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures.Add(1) <= 3 {
+			http.Error(w, "down", http.StatusBadGateway)
+			return
+		}
+		upstream.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer flaky.Close()
+	artistsURL, relationsURL = flaky.URL+"/artists", flaky.URL+"/relation"
+	if err := saveSnapshot("earlier", []Artist{{ID: 1, Name: "Snapshot Artist"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadData(); err == nil {
+		t.Fatal("LoadData() from a failing upstream API succeeded")
+	}
+	if _, err := loadSnapshot(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once the upstream API is back its data replaces the snapshot, even without refreshes
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	RunRefresher(ctx, 0)
+	if ctx.Err() != nil {
+		t.Fatal("RunRefresher() did not stop after loading the data")
+	}
+	if d := currentData(); len(d.artists) != 1 || d.artists[0].Name != "Test Artist" {
+		t.Errorf("artists after the upstream API recovered = %+v, want the upstream one", d.artists)
+	}
+	if r := checkReadiness(); r.Source != flaky.URL {
+		t.Errorf("readiness source = %q, want the upstream API %s", r.Source, flaky.URL)
+	}
+}
+
+func TestInfoAboutArtist_Fallback(t *testing.T) {
+	useTemplates(t, "details.html")
+	list, rels := concertData()
+	list[0].Locations = "http://127.0.0.1:1/locations/1"
+	d := useData(list, rels)
+
+	w := httptest.NewRecorder()
+	Routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/artists/queen", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("artist page without the upstream API = %d, want the loaded concerts", w.Code)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "Los Angeles") {
+		t.Errorf("artist page doesn't list the loaded concerts: %s", body)
+	}
+
+	locations, dates, _, err := d.fetchArtistDetails(context.Background(), list[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(locations.Locations, ",") != "los_angeles-usa,saint_denis-france" {
+		t.Errorf("locations = %v, want the sorted relation keys", locations.Locations)
+	}
+	if strings.Join(dates.Dates, ",") != "*23-08-2019,22-08-2019,*05-07-2020" {
+		t.Errorf("dates = %v, want the relation dates with the first of each location starred", dates.Dates)
+	}
+
+	// Without loaded concerts the error stays
+	if _, _, _, err := d.fetchArtistDetails(context.Background(), Artist{ID: 9, Locations: "http://127.0.0.1:1/locations/9"}); err == nil {
+		t.Error("fetchArtistDetails() of an unknown artist without the upstream API succeeded")
+	}
+}
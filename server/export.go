@@ -0,0 +1,217 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// listSeparator joins list values such as members into a single CSV cell.
+const listSeparator = "; "
+
+// exportWriter streams the rows of one export for the selected artists.
+type exportWriter func(w io.Writer, list []Artist, rels map[int]Relation) error
+
+// exportFormat describes a downloadable export.
+type exportFormat struct {
+	contentType string
+	write       exportWriter
+}
+
+// exports maps export file names to their formats.
+var exports = map[string]exportFormat{
+	"artists.csv":    {"text/csv; charset=utf-8", writeArtistsCSV},
+	"artists.jsonl":  {"application/x-ndjson", writeArtistsJSONL},
+	"concerts.csv":   {"text/csv; charset=utf-8", writeConcertsCSV},
+	"concerts.jsonl": {"application/x-ndjson", writeConcertsJSONL},
+}
+
+// ExportNames returns the names of the available exports in alphabetical order.
+func ExportNames() []string {
+	names := make([]string, 0, len(exports))
+	for name := range exports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// artistRecord is one exported artist row.
+type artistRecord struct {
+	ID           int      `json:"id"`
+	Name         string   `json:"name"`
+	Members      []string `json:"members"`
+	MemberCount  int      `json:"memberCount"`
+	CreationDate int      `json:"creationDate"`
+	FirstAlbum   string   `json:"firstAlbum"`
+	ConcertCount int      `json:"concertCount"`
+	Locations    []string `json:"locations"`
+}
+
+// concertRecord is one exported concert row.
+type concertRecord struct {
+	ArtistID     int    `json:"artistId"`
+	Artist       string `json:"artist"`
+	Location     string `json:"location"`
+	LocationName string `json:"locationName"`
+	Date         string `json:"date"`
+}
+
+// newArtistRecord builds the export row of an artist from its data and relation.
+func newArtistRecord(artist Artist, rel Relation) artistRecord {
+	record := artistRecord{
+		ID:           artist.ID,
+		Name:         artist.Name,
+		Members:      artist.Members,
+		MemberCount:  len(artist.Members),
+		CreationDate: artist.CreationDate,
+		FirstAlbum:   artist.FirstAlbum,
+		Locations:    []string{},
+	}
+	if album, err := parseDate(artist.FirstAlbum); err == nil {
+		record.FirstAlbum = album.Format("2006-01-02")
+	}
+	for location, dates := range rel.DatesLocation {
+		record.Locations = append(record.Locations, location)
+		record.ConcertCount += len(dates)
+	}
+	sort.Strings(record.Locations)
+	return record
+}
+
+// newConcertRecord builds the export row of a concert.
+func newConcertRecord(c Concert) concertRecord {
+	return concertRecord{
+		ArtistID:     c.ArtistID,
+		Artist:       c.Artist,
+		Location:     c.Location,
+		LocationName: c.LocationName(),
+		Date:         c.Date.Format("2006-01-02"),
+	}
+}
+
+// concertRecords builds the export rows of the concerts of the selected artists.
+func concertRecords(list []Artist, rels map[int]Relation) []concertRecord {
+	var records []concertRecord
+	for _, artist := range list {
+		for _, c := range concertsOf(artist, rels[artist.ID]) {
+			records = append(records, newConcertRecord(c))
+		}
+	}
+	return records
+}
+
+// writeArtistsCSV writes one CSV row per artist, members joined into a single cell.
+func writeArtistsCSV(w io.Writer, list []Artist, rels map[int]Relation) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "name", "members", "member_count", "creation_date", "first_album", "concert_count", "locations"})
+	for _, artist := range list {
+		r := newArtistRecord(artist, rels[artist.ID])
+		cw.Write([]string{
+			strconv.Itoa(r.ID),
+			r.Name,
+			strings.Join(r.Members, listSeparator),
+			strconv.Itoa(r.MemberCount),
+			strconv.Itoa(r.CreationDate),
+			r.FirstAlbum,
+			strconv.Itoa(r.ConcertCount),
+			strings.Join(r.Locations, listSeparator),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeArtistsJSONL writes one JSON object per artist and line.
+func writeArtistsJSONL(w io.Writer, list []Artist, rels map[int]Relation) error {
+	enc := json.NewEncoder(w)
+	for _, artist := range list {
+		if err := enc.Encode(newArtistRecord(artist, rels[artist.ID])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeConcertsCSV writes one CSV row per concert.
+func writeConcertsCSV(w io.Writer, list []Artist, rels map[int]Relation) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"artist_id", "artist", "location", "location_name", "date"})
+	for _, r := range concertRecords(list, rels) {
+		cw.Write([]string{strconv.Itoa(r.ArtistID), r.Artist, r.Location, r.LocationName, r.Date})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeConcertsJSONL writes one JSON object per concert and line.
+func writeConcertsJSONL(w io.Writer, list []Artist, rels map[int]Relation) error {
+	enc := json.NewEncoder(w)
+	for _, r := range concertRecords(list, rels) {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteExport writes the named export (e.g. "concerts.csv") for the artists selected by
+// the filter parameters in query. It backs the export subcommand of the command line.
+func WriteExport(w io.Writer, name string, query url.Values) error {
+	format, ok := exports[name]
+	if !ok {
+		return fmt.Errorf("unknown export %q, want one of %s", name, strings.Join(ExportNames(), ", "))
+	}
+	filter, err := parseFilter(query)
+	if err != nil {
+		return err
+	}
+	list, rels, err := currentData().filterArtists(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	if err := format.write(bw, list, rels); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Export streams the artists or concerts as CSV or JSON Lines, honoring the filter parameters.
+func Export(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	format, ok := exports[name]
+	if !ok {
+		ErrorPage(w, http.StatusNotFound)
+		return
+	}
+
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		loggerFrom(r.Context()).Warn("invalid filter", "err", err)
+		ErrorPage(w, http.StatusBadRequest)
+		return
+	}
+
+	list, rels, err := currentData().filterArtists(r.Context(), filter)
+	if err != nil {
+		loggerFrom(r.Context()).Error("could not filter the artists", "err", err)
+		ErrorPage(w, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	// Rows go straight to the client; an error here can only be logged
+	if err := format.write(w, list, rels); err != nil {
+		loggerFrom(r.Context()).Error("could not write the export", "format", name, "err", err)
+	}
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"groupie-tracker/gazetteer"
+)
+
+func TestExport(t *testing.T) {
+	setupConcertData()
+
+	tests := []struct {
+		name         string
+		path         string
+		expectedCode int
+		expectedType string
+		expectedRows int
+	}{
+		{"Artists CSV", "/export/artists.csv", http.StatusOK, "text/csv", 3},
+		{"Artists CSV filtered", "/export/artists.csv?members=1", http.StatusOK, "text/csv", 2},
+		{"Concerts CSV", "/export/concerts.csv", http.StatusOK, "text/csv", 5},
+		{"Artists JSONL", "/export/artists.jsonl", http.StatusOK, "application/x-ndjson", 2},
+		{"Concerts JSONL filtered", "/export/concerts.jsonl?ids=1", http.StatusOK, "application/x-ndjson", 3},
+		{"Unknown export", "/export/artists.xml", http.StatusNotFound, "", 0},
+		{"Invalid filter", "/export/artists.csv?created_from=abc", http.StatusBadRequest, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+			Routes().ServeHTTP(w, r)
+			if w.Code != tt.expectedCode {
+				t.Fatalf("Export() status code = %d, want %d", w.Code, tt.expectedCode)
+			}
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.expectedType) {
+				t.Errorf("Content-Type = %q, want %q", ct, tt.expectedType)
+			}
+			rows := strings.Count(w.Body.String(), "\n")
+			if rows != tt.expectedRows {
+				t.Errorf("got %d rows, want %d", rows, tt.expectedRows)
+			}
+		})
+	}
+}
+
+func TestWriteExport_ArtistsCSV(t *testing.T) {
+	setupConcertData()
+
+	var buf bytes.Buffer
+	if err := WriteExport(&buf, "artists.csv", url.Values{"ids": {"1"}}); err != nil {
+		t.Fatalf("WriteExport() error = %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v", err)
+	}
+	expected := []string{"1", "Queen", "Freddie Mercury; Brian May", "2", "1970", "1973-12-14", "3", "los_angeles-usa; saint_denis-france"}
+	if strings.Join(records[1], "|") != strings.Join(expected, "|") {
+		t.Errorf("artist row = %q, want %q", records[1], expected)
+	}
+}
+
+func TestWriteExport_ConcertsJSONL(t *testing.T) {
+	setupConcertData()
+	places = gazetteer.Default()
+
+	var buf bytes.Buffer
+	if err := WriteExport(&buf, "concerts.jsonl", url.Values{"ids": {"2"}}); err != nil {
+		t.Fatalf("WriteExport() error = %v", err)
+	}
+	var record concertRecord
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	expected := concertRecord{ArtistID: 2, Artist: "Pink Floyd", Location: "london-uk", LocationName: "London, United Kingdom", Date: "2021-01-01"}
+	if record != expected {
+		t.Errorf("concert = %+v, want %+v", record, expected)
+	}
+}
+
+func TestWriteExport_UnknownName(t *testing.T) {
+	setupConcertData()
+
+	if err := WriteExport(&bytes.Buffer{}, "artists.xml", nil); err == nil {
+		t.Errorf("WriteExport() expected an error for an unknown export")
+	}
+}
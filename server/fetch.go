@@ -1,107 +1,234 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"io/fs"
 	"net/http"
-	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"text/template"
+	"time"
+
+	"groupie-tracker/web"
 )
 
-// Global variables to hold templates and artist data
-var templates map[string]*template.Template
-var artists []Artist
+// Global variable to hold the templates
+var templates atomic.Pointer[templateSet]
 
 var artistsURL = "https://groupietrackers.herokuapp.com/api/artists"
+var relationsURL = "https://groupietrackers.herokuapp.com/api/relation"
 
-// init initializes templates and fetches artist data when the package is loaded.
-func init() {
-	var err error
-	// Load HTML templates into the templates map
-	templates, err = loadTemplates()
-	if err != nil {
-		log.Fatal(err)
-	}
+// templatesFS holds the HTML templates
+var templatesFS = web.Templates("")
 
-	if err := FetchArtists(); err != nil {
-		log.Fatal("could not fetch artists: ", err)
-	}
+// upstreamClient makes the requests to the upstream API, giving up on those that hang
+var upstreamClient = &http.Client{Timeout: 10 * time.Second}
+
+// cacheTTL is how long the responses of the per-artist endpoints are reused, zero disables the cache
+var cacheTTL time.Duration
+
+// cachedResponse is an upstream response body and when it was fetched.
+type cachedResponse struct {
+	body    []byte
+	fetched time.Time
 }
 
-// loadTemplates loads HTML templates from the templates directory.
+var (
+	cacheMu       sync.Mutex
+	responseCache = make(map[string]cachedResponse)
+)
+
+// loadTemplates loads the HTML templates, each page combined with the layout.
 func loadTemplates() (map[string]*template.Template, error) {
-	templates = make(map[string]*template.Template)
-	layout := "templates/layout.html"
+	pages := make(map[string]*template.Template)
 
-	// Get all HTML files in the "templates" directory
-	pages, err := filepath.Glob("templates/*.html")
+	// Get all HTML files of the templates
+	files, err := fs.Glob(templatesFS, "*.html")
 	if err != nil {
 		return nil, fmt.Errorf("failed to load template files: %w", err)
 	}
 
-	for _, page := range pages {
-		if page == layout {
+	for _, page := range files {
+		if page == "layout.html" {
 			continue
 		}
-		// Combine layout with the current page template and parse the templates
-		files := []string{layout, page}
-		tmpl, err := template.ParseFiles(files...)
+		tmpl, err := parsePage(page)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse template %s: %w", page, err)
+			return nil, err
 		}
-		//Store the parsed template in the map using the base file name as key
-		templates[filepath.Base(page)] = tmpl
+		// Store the parsed template in the map using the file name as key
+		pages[page] = tmpl
+	}
+	return pages, nil
+}
+
+// parsePage parses a page combined with the layout, except the error page which
+// stands alone so that it works whatever broke.
+func parsePage(page string) (*template.Template, error) {
+	files := []string{"layout.html", page}
+	if page == "errors.html" {
+		files = files[1:]
+	}
+	tmpl, err := template.ParseFS(templatesFS, files...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", page, err)
 	}
-	return templates, nil
+	return tmpl, nil
 }
 
-// Fetches data from the given URL and unmarshals it into the target struct.
-func fetchData(url string, target interface{}) error {
-	response, err := http.Get(url)
+// Fetches data from the given URL and unmarshals it into the target struct,
+// giving up when the context is done.
+func fetchData(ctx context.Context, url string, target interface{}) error {
+	bytes, err := fetchBody(ctx, url)
+	if err != nil {
+		return err
+	}
+
+	// Unmarshal JSON into target struct
+	if err := json.Unmarshal(bytes, target); err != nil {
+		return fmt.Errorf("failed to unmarshal data from %s: %w", url, err)
+	}
+
+	return nil
+}
+
+// fetchBody returns the response body of a successful GET request to the URL; any
+// other status than 2xx is an error. The duration and failures are recorded in the metrics.
+func fetchBody(ctx context.Context, url string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch data from %s: %w", url, err)
+	}
+	start := time.Now()
+	response, err := upstreamClient.Do(request)
 	if err != nil {
-		return fmt.Errorf("failed to fetch data from %s: %w", url, err)
+		observeUpstream(url, start, true)
+		return nil, fmt.Errorf("failed to fetch data from %s: %w", url, err)
 	}
 
 	defer response.Body.Close()
 
 	// Read response body into bytes slice
 	bytes, err := io.ReadAll(response.Body)
+	ok := response.StatusCode >= 200 && response.StatusCode < 300
+	observeUpstream(url, start, err != nil || !ok)
 	if err != nil {
-		return fmt.Errorf("failed to read response body from %s: %w", url, err)
+		return nil, fmt.Errorf("failed to read response body from %s: %w", url, err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("failed to fetch data from %s: %s", url, response.Status)
 	}
+	return bytes, nil
+}
 
-	// Unmarshal JSON into target struct
+// fetchCached works like fetchData but reuses the responses fetched within cacheTTL.
+func fetchCached(ctx context.Context, url string, target interface{}) error {
+	cacheMu.Lock()
+	cached, ok := responseCache[url]
+	cacheMu.Unlock()
+	if ok && time.Since(cached.fetched) < cacheTTL {
+		cacheLookups.Inc("hit")
+		return json.Unmarshal(cached.body, target)
+	}
+	if cacheTTL > 0 {
+		cacheLookups.Inc("miss")
+	}
+
+	bytes, err := fetchBody(ctx, url)
+	if err != nil {
+		return err
+	}
 	if err := json.Unmarshal(bytes, target); err != nil {
 		return fmt.Errorf("failed to unmarshal data from %s: %w", url, err)
 	}
-
+	// Only responses that could be read are worth keeping
+	if cacheTTL > 0 {
+		cacheMu.Lock()
+		responseCache[url] = cachedResponse{body: bytes, fetched: time.Now()}
+		cacheMu.Unlock()
+	}
 	return nil
 }
 
-// FetchArtists retrieves artist data from the defined artistsURL using fetchData
-func FetchArtists() error {
-	return fetchData(artistsURL, &artists)
+// FetchArtists retrieves the list of artists from the defined artistsURL using fetchData
+func FetchArtists(ctx context.Context) ([]Artist, error) {
+	var list []Artist
+	err := fetchData(ctx, artistsURL, &list)
+	return list, err
 }
 
-// FetchRelation retrieves relation data from a specified URL using fetchData
-func FetchLocations(url string) (Loc, error) {
+// fetchRelations retrieves the relations of all artists keyed by artist ID
+func fetchRelations(ctx context.Context) (map[int]Relation, error) {
+	var index RelationIndex
+	if err := fetchData(ctx, relationsURL, &index); err != nil {
+		return nil, err
+	}
+	rels := make(map[int]Relation, len(index.Index))
+	for _, rel := range index.Index {
+		rels[rel.ID] = rel
+	}
+	return rels, nil
+}
+
+// FetchLocations retrieves location data from a specified URL using fetchCached
+func FetchLocations(ctx context.Context, url string) (Loc, error) {
 	var location Loc
-	err := fetchData(url, &location)
+	err := fetchCached(ctx, url, &location)
 	return location, err
 }
 
-// FetchRelation retrieves relation data from a specified URL using fetchData
-func FetchRelation(url string) (Relation, error) {
+// FetchRelation retrieves relation data from a specified URL using fetchCached
+func FetchRelation(ctx context.Context, url string) (Relation, error) {
 	var relation Relation
-	err := fetchData(url, &relation)
+	err := fetchCached(ctx, url, &relation)
 	return relation, err
 }
 
-// FetchDates retrieves date data from a specified URL using fetchData
-func FetchDates(url string) (Date, error) {
+// FetchDates retrieves date data from a specified URL using fetchCached
+func FetchDates(ctx context.Context, url string) (Date, error) {
 	var dates Date
-	err := fetchData(url, &dates)
+	err := fetchCached(ctx, url, &dates)
 	return dates, err
 }
+
+// fetchArtistDetails retrieves the locations, dates and concerts of an artist. When
+// the upstream API fails they are derived from the relations already loaded, so that
+// the page still works from a snapshot; the error is only returned without them.
+func (d *dataset) fetchArtistDetails(ctx context.Context, artist Artist) (Loc, Date, Relation, error) {
+	locations, err := FetchLocations(ctx, artist.Locations)
+	var dates Date
+	var rel Relation
+	if err == nil {
+		dates, err = FetchDates(ctx, artist.ConcertDates)
+	}
+	if err == nil {
+		rel, err = FetchRelation(ctx, artist.Relations)
+	}
+	if err == nil {
+		return locations, dates, rel, nil
+	}
+	rel, ok := d.relations[artist.ID]
+	if !ok {
+		return Loc{}, Date{}, Relation{}, err
+	}
+	loggerFrom(ctx).Warn("serving the loaded concerts", "artist", artist.Name, "err", err)
+	locations, dates = Loc{}, Date{}
+	for place := range rel.DatesLocation {
+		locations.Locations = append(locations.Locations, place)
+	}
+	sort.Strings(locations.Locations)
+	// Like the upstream API, a star marks the first date at each location
+	for _, place := range locations.Locations {
+		for i, date := range rel.DatesLocation[place] {
+			if i == 0 {
+				date = "*" + date
+			}
+			dates.Dates = append(dates.Dates, date)
+		}
+	}
+	return locations, dates, rel, nil
+}
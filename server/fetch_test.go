@@ -1,46 +1,27 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
-	"os"
-	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 func TestLoadTemplates(t *testing.T) {
-	// Create temporary directory for test templates
-	tempDir, err := os.MkdirTemp("", "test_templates")
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer os.RemoveAll(tempDir)
-
-	// Create "templates" subdirectory
-	templatesDir := filepath.Join(tempDir, "templates")
-	if err := os.Mkdir(templatesDir, 0o755); err != nil {
-		t.Fatal(err)
+	// Use test templates instead of the embedded ones
+	oldFS := templatesFS
+	defer func() { templatesFS = oldFS }()
+	templatesFS = fstest.MapFS{
+		"layout.html": {Data: []byte("layout")},
+		"page.html":   {Data: []byte("page")},
 	}
 
-	// Create test template files
-	layoutPath := filepath.Join(templatesDir, "layout.html")
-	pagePath := filepath.Join(templatesDir, "page.html")
-
-	if err := os.WriteFile(layoutPath, []byte("layout"), 0o644); err != nil {
-		t.Fatal(err)
-	}
-	if err := os.WriteFile(pagePath, []byte("page"), 0o644); err != nil {
-		t.Fatal(err)
-	}
-
-	// Set working directory to temp dir
-	oldWd, _ := os.Getwd()
-	os.Chdir(tempDir)
-	defer os.Chdir(oldWd)
-
 	// Test loadTemplates
 	templates, err := loadTemplates()
 	if err != nil {
@@ -63,7 +44,7 @@ func TestFetchData(t *testing.T) {
 
 	// Test fetchData
 	var result map[string]string
-	err := fetchData(ts.URL, &result)
+	err := fetchData(context.Background(), ts.URL, &result)
 	if err != nil {
 		t.Fatalf("fetchData() error = %v", err)
 	}
@@ -73,6 +54,35 @@ func TestFetchData(t *testing.T) {
 	}
 }
 
+func TestFetchBody_ErrorStatus(t *testing.T) {
+	// An error status with a JSON body must not be taken for the data
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, `[]`)
+	}))
+	defer ts.Close()
+
+	body, err := fetchBody(context.Background(), ts.URL)
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("fetchBody() = %q, %v, want an error naming the status", body, err)
+	}
+}
+
+func TestFetchBody_Timeout(t *testing.T) {
+	// An upstream that never answers must not hold the request forever
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+	oldClient := upstreamClient
+	defer func() { upstreamClient = oldClient }()
+	upstreamClient = &http.Client{Timeout: 10 * time.Millisecond}
+
+	if _, err := fetchBody(context.Background(), ts.URL); err == nil {
+		t.Error("fetchBody() of a hung upstream succeeded")
+	}
+}
+
 func TestFetchArtists(t *testing.T) {
 	// Create test server
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -86,12 +96,12 @@ func TestFetchArtists(t *testing.T) {
 	defer func() { artistsURL = oldURL }()
 
 	// Test FetchArtists
-	err := FetchArtists()
+	list, err := FetchArtists(context.Background())
 	if err != nil {
 		t.Fatalf("FetchArtists() error = %v", err)
 	}
-	if len(artists) != 1 || artists[0].Name != "Test Artist" {
-		t.Errorf("FetchArtists() did not populate artists correctly")
+	if len(list) != 1 || list[0].Name != "Test Artist" {
+		t.Errorf("FetchArtists() did not return the artists correctly")
 	}
 }
 
@@ -103,12 +113,12 @@ func TestFetchLocations(t *testing.T) {
 	defer ts.Close()
 
 	// Test FetchLocations
-	loc, err := FetchLocations(ts.URL)
+	loc, err := FetchLocations(context.Background(), ts.URL)
 	if err != nil {
-		t.Fatalf("FetchLocations() error = %v", err)
+		t.Fatalf("FetchLocations(context.Background(), ) error = %v", err)
 	}
 	if len(loc.Locations) != 1 || loc.Locations[0] != "Test Location" {
-		t.Errorf("FetchLocations() returned incorrect data")
+		t.Errorf("FetchLocations(context.Background(), ) returned incorrect data")
 	}
 }
 
@@ -120,12 +130,12 @@ func TestFetchRelation(t *testing.T) {
 	defer ts.Close()
 
 	// Test FetchRelation
-	relation, err := FetchRelation(ts.URL)
+	relation, err := FetchRelation(context.Background(), ts.URL)
 	if err != nil {
-		t.Fatalf("FetchRelation() error = %v", err)
+		t.Fatalf("FetchRelation(context.Background(), ) error = %v", err)
 	}
 	if len(relation.DatesLocation) != 1 || len(relation.DatesLocation["Test"]) != 1 || relation.DatesLocation["Test"][0] != "2023" {
-		t.Errorf("FetchRelation() returned incorrect data")
+		t.Errorf("FetchRelation(context.Background(), ) returned incorrect data")
 	}
 }
 
@@ -137,11 +147,49 @@ func TestFetchDates(t *testing.T) {
 	defer ts.Close()
 
 	// Test FetchDates
-	dates, err := FetchDates(ts.URL)
+	dates, err := FetchDates(context.Background(), ts.URL)
 	if err != nil {
-		t.Fatalf("FetchDates() error = %v", err)
+		t.Fatalf("FetchDates(context.Background(), ) error = %v", err)
 	}
 	if len(dates.Dates) != 1 || dates.Dates[0] != "2023-01-01" {
-		t.Errorf("FetchDates() returned incorrect data")
+		t.Errorf("FetchDates(context.Background(), ) returned incorrect data")
+	}
+}
+
+func TestFetchCached(t *testing.T) {
+	upstream := newFakeUpstream(t)
+	useUpstream(t, upstream)
+	url := upstream.URL + "/locations/1"
+
+	cacheTTL = time.Hour
+	for i := 0; i < 3; i++ {
+		if loc, err := FetchLocations(context.Background(), url); err != nil || len(loc.Locations) != 1 {
+			t.Fatalf("FetchLocations(context.Background(), ) = %+v, %v", loc, err)
+		}
+	}
+	if n := upstream.requests.Load(); n != 1 {
+		t.Errorf("upstream got %d requests within the TTL, want 1", n)
+	}
+
+	// An expired response is fetched again
+	cacheMu.Lock()
+	cached := responseCache[url]
+	cached.fetched = time.Now().Add(-2 * time.Hour)
+	responseCache[url] = cached
+	cacheMu.Unlock()
+	if _, err := FetchLocations(context.Background(), url); err != nil {
+		t.Fatal(err)
+	}
+	if n := upstream.requests.Load(); n != 2 {
+		t.Errorf("upstream got %d requests after the TTL, want 2", n)
+	}
+
+	// Nothing is kept when caching is disabled
+	cacheTTL = 0
+	other := upstream.URL + "/dates/1"
+	FetchDates(context.Background(), other)
+	FetchDates(context.Background(), other)
+	if n := upstream.requests.Load(); n != 4 {
+		t.Errorf("upstream got %d requests without a cache, want 4", n)
 	}
 }
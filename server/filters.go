@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ArtistFilter selects artists using the query parameters shared by the feeds and exports:
+//
+//	ids=1,7,12          only these artist IDs
+//	q=queen             name contains the text
+//	members=4,5         number of members
+//	created_from=1970   creation year range, inclusive
+//	created_to=1990
+//	album_from=1980     first album year range, inclusive
+//	album_to=2000
+//	location=usa        played a location whose slug contains the text
+type ArtistFilter struct {
+	IDs         map[int]bool
+	Name        string
+	Members     map[int]bool
+	CreatedFrom int
+	CreatedTo   int
+	AlbumFrom   int
+	AlbumTo     int
+	Location    string
+}
+
+// parseFilter builds an ArtistFilter from query parameters.
+func parseFilter(query url.Values) (ArtistFilter, error) {
+	var f ArtistFilter
+	var err error
+
+	if f.IDs, err = parseIntSet(query.Get("ids")); err != nil {
+		return f, fmt.Errorf("invalid ids: %w", err)
+	}
+	if f.Members, err = parseIntSet(query.Get("members")); err != nil {
+		return f, fmt.Errorf("invalid members: %w", err)
+	}
+
+	years := []struct {
+		name   string
+		target *int
+	}{
+		{"created_from", &f.CreatedFrom},
+		{"created_to", &f.CreatedTo},
+		{"album_from", &f.AlbumFrom},
+		{"album_to", &f.AlbumTo},
+	}
+	for _, y := range years {
+		value := query.Get(y.name)
+		if value == "" {
+			continue
+		}
+		if *y.target, err = strconv.Atoi(value); err != nil {
+			return f, fmt.Errorf("invalid %s: %w", y.name, err)
+		}
+	}
+
+	f.Name = strings.ToLower(strings.TrimSpace(query.Get("q")))
+	f.Location = strings.ToLower(strings.TrimSpace(query.Get("location")))
+	return f, nil
+}
+
+// parseIntSet parses a comma-separated list of integers; an empty list yields nil.
+func parseIntSet(list string) (map[int]bool, error) {
+	if strings.TrimSpace(list) == "" {
+		return nil, nil
+	}
+	set := make(map[int]bool)
+	for _, part := range strings.Split(list, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		set[n] = true
+	}
+	return set, nil
+}
+
+// Match reports whether an artist and its concerts pass the filter.
+func (f ArtistFilter) Match(artist Artist, rel Relation) bool {
+	if f.IDs != nil && !f.IDs[artist.ID] {
+		return false
+	}
+	if f.Name != "" && !strings.Contains(strings.ToLower(artist.Name), f.Name) {
+		return false
+	}
+	if f.Members != nil && !f.Members[len(artist.Members)] {
+		return false
+	}
+	if !inRange(artist.CreationDate, f.CreatedFrom, f.CreatedTo) {
+		return false
+	}
+	if f.AlbumFrom != 0 || f.AlbumTo != 0 {
+		album, err := parseDate(artist.FirstAlbum)
+		if err != nil || !inRange(album.Year(), f.AlbumFrom, f.AlbumTo) {
+			return false
+		}
+	}
+	if f.Location != "" {
+		for location := range rel.DatesLocation {
+			if strings.Contains(location, f.Location) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// inRange reports whether n lies within [from, to]; a zero bound is open.
+func inRange(n, from, to int) bool {
+	return (from == 0 || n >= from) && (to == 0 || n <= to)
+}
+
+// filterArtists returns the artists matching the filter together with their relations.
+func (d *dataset) filterArtists(ctx context.Context, f ArtistFilter) ([]Artist, map[int]Relation, error) {
+	var matched []Artist
+	rels := make(map[int]Relation)
+	for _, artist := range d.artists {
+		if f.IDs != nil && !f.IDs[artist.ID] {
+			continue
+		}
+		rel, err := d.relationFor(ctx, artist)
+		if err != nil {
+			return nil, nil, err
+		}
+		if f.Match(artist, rel) {
+			matched = append(matched, artist)
+			rels[artist.ID] = rel
+		}
+	}
+	return matched, rels, nil
+}
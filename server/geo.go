@@ -0,0 +1,184 @@
+package server
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"groupie-tracker/gazetteer"
+)
+
+// geoFeatureCollection is a GeoJSON FeatureCollection (RFC 7946).
+type geoFeatureCollection struct {
+	Type     string       `json:"type"`
+	Features []geoFeature `json:"features"`
+}
+
+// geoFeature is a GeoJSON point feature describing one concert.
+type geoFeature struct {
+	Type       string         `json:"type"`
+	Geometry   geoPoint       `json:"geometry"`
+	Properties geoConcertInfo `json:"properties"`
+}
+
+type geoPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"` // longitude, latitude
+}
+
+type geoConcertInfo struct {
+	ArtistID     int    `json:"artistId"`
+	Artist       string `json:"artist"`
+	Location     string `json:"location"`
+	LocationName string `json:"locationName"`
+	Date         string `json:"date"`
+}
+
+// kmlDocument is the root of a KML 2.2 file.
+type kmlDocument struct {
+	XMLName    xml.Name       `xml:"kml"`
+	Namespace  string         `xml:"xmlns,attr"`
+	Name       string         `xml:"Document>name"`
+	Placemarks []kmlPlacemark `xml:"Document>Placemark"`
+}
+
+// kmlPlacemark is a KML point describing one concert.
+type kmlPlacemark struct {
+	Name        string    `xml:"name"`
+	Description string    `xml:"description"`
+	When        string    `xml:"TimeStamp>when"`
+	Data        []kmlData `xml:"ExtendedData>Data"`
+	Coordinates string    `xml:"Point>coordinates"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+// locatedConcert pairs a concert with the coordinates of its location.
+type locatedConcert struct {
+	Concert
+	Place gazetteer.Place
+}
+
+// locateConcerts looks up the coordinates of each concert location,
+// logging and skipping locations the gazetteer does not know.
+func locateConcerts(concerts []Concert) []locatedConcert {
+	var located []locatedConcert
+	missing := make(map[string]bool)
+	for _, c := range concerts {
+		place, ok := places.Lookup(c.Location)
+		if !ok {
+			if !missing[c.Location] {
+				slog.Warn("no coordinates for location", "location", c.Location)
+				missing[c.Location] = true
+			}
+			continue
+		}
+		located = append(located, locatedConcert{Concert: c, Place: place})
+	}
+	return located
+}
+
+// writeGeoJSON writes concerts as a GeoJSON FeatureCollection of points.
+func writeGeoJSON(w io.Writer, concerts []Concert) error {
+	collection := geoFeatureCollection{Type: "FeatureCollection", Features: []geoFeature{}}
+	for _, c := range locateConcerts(concerts) {
+		collection.Features = append(collection.Features, geoFeature{
+			Type:     "Feature",
+			Geometry: geoPoint{Type: "Point", Coordinates: [2]float64{c.Place.Lon, c.Place.Lat}},
+			Properties: geoConcertInfo{
+				ArtistID:     c.ArtistID,
+				Artist:       c.Artist,
+				Location:     c.Location,
+				LocationName: c.LocationName(),
+				Date:         c.Date.Format("2006-01-02"),
+			},
+		})
+	}
+	return json.NewEncoder(w).Encode(collection)
+}
+
+// writeKML writes concerts as a KML document with one placemark per concert.
+func writeKML(w io.Writer, name string, concerts []Concert) error {
+	doc := kmlDocument{Namespace: "http://www.opengis.net/kml/2.2", Name: name}
+	for _, c := range locateConcerts(concerts) {
+		date := c.Date.Format("2006-01-02")
+		doc.Placemarks = append(doc.Placemarks, kmlPlacemark{
+			Name:        c.Artist + " - " + c.LocationName(),
+			Description: c.Artist + " live in " + c.LocationName() + " on " + date,
+			When:        date,
+			Data: []kmlData{
+				{Name: "artistId", Value: fmt.Sprint(c.ArtistID)},
+				{Name: "artist", Value: c.Artist},
+				{Name: "location", Value: c.Location},
+				{Name: "date", Value: date},
+			},
+			Coordinates: fmt.Sprintf("%g,%g", c.Place.Lon, c.Place.Lat),
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
+
+// serveGeoJSON writes the GeoJSON response of a set of concerts.
+func serveGeoJSON(w http.ResponseWriter, concerts []Concert) {
+	w.Header().Set("Content-Type", "application/geo+json")
+	if err := writeGeoJSON(w, concerts); err != nil {
+		slog.Error("could not write the GeoJSON", "err", err)
+	}
+}
+
+// serveKML writes the KML response of a set of concerts.
+func serveKML(w http.ResponseWriter, name string, concerts []Concert) {
+	w.Header().Set("Content-Type", "application/vnd.google-earth.kml+xml")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", slugify(name)+".kml"))
+	if err := writeKML(w, name, concerts); err != nil {
+		slog.Error("could not write the KML", "err", err)
+	}
+}
+
+// ArtistConcertsGeoJSON serves the concert locations of one artist as GeoJSON.
+func ArtistConcertsGeoJSON(w http.ResponseWriter, r *http.Request) {
+	_, concerts, ok := artistConcerts(w, r)
+	if !ok {
+		return
+	}
+	serveGeoJSON(w, concerts)
+}
+
+// ArtistConcertsKML serves the concert locations of one artist as KML.
+func ArtistConcertsKML(w http.ResponseWriter, r *http.Request) {
+	artist, concerts, ok := artistConcerts(w, r)
+	if !ok {
+		return
+	}
+	serveKML(w, artist.Name+" concerts", concerts)
+}
+
+// ConcertsGeoJSON serves the concert locations of all artists matching the filter parameters as GeoJSON.
+func ConcertsGeoJSON(w http.ResponseWriter, r *http.Request) {
+	concerts, ok := currentData().filteredConcerts(w, r)
+	if !ok {
+		return
+	}
+	serveGeoJSON(w, concerts)
+}
+
+// ConcertsKML serves the concert locations of all artists matching the filter parameters as KML.
+func ConcertsKML(w http.ResponseWriter, r *http.Request) {
+	concerts, ok := currentData().filteredConcerts(w, r)
+	if !ok {
+		return
+	}
+	serveKML(w, "Groupie Tracker concerts", concerts)
+}
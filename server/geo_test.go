@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"groupie-tracker/gazetteer"
+)
+
+func TestArtistConcertsGeoJSON(t *testing.T) {
+	setupConcertData()
+	places = gazetteer.Default()
+
+	r := httptest.NewRequest(http.MethodGet, "/artists/queen/concerts.geojson", nil)
+	r.SetPathValue("artist", "queen")
+	w := httptest.NewRecorder()
+	ArtistConcertsGeoJSON(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("ArtistConcertsGeoJSON() status code = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var collection geoFeatureCollection
+	if err := json.Unmarshal(w.Body.Bytes(), &collection); err != nil {
+		t.Fatalf("response is not valid JSON: %v", err)
+	}
+	if collection.Type != "FeatureCollection" {
+		t.Errorf("type = %q, want FeatureCollection", collection.Type)
+	}
+	// saint_denis-france is not in the gazetteer and is skipped
+	if len(collection.Features) != 2 {
+		t.Fatalf("got %d features, want 2", len(collection.Features))
+	}
+
+	feature := collection.Features[0]
+	if feature.Geometry.Type != "Point" {
+		t.Errorf("geometry type = %q, want Point", feature.Geometry.Type)
+	}
+	if lon, lat := feature.Geometry.Coordinates[0], feature.Geometry.Coordinates[1]; lon > -118 || lon < -119 || lat < 34 || lat > 35 {
+		t.Errorf("coordinates = %v, want Los Angeles as [lon, lat]", feature.Geometry.Coordinates)
+	}
+	expected := geoConcertInfo{ArtistID: 1, Artist: "Queen", Location: "los_angeles-usa", LocationName: "Los Angeles, United States", Date: "2019-08-22"}
+	if feature.Properties != expected {
+		t.Errorf("properties = %+v, want %+v", feature.Properties, expected)
+	}
+}
+
+func TestConcertsKML(t *testing.T) {
+	setupConcertData()
+
+	tests := []struct {
+		name               string
+		query              string
+		expectedCode       int
+		expectedPlacemarks int
+	}{
+		{"All artists", "", http.StatusOK, 3},
+		{"Filtered", "?ids=2", http.StatusOK, 1},
+		{"Invalid filter", "?members=two", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/concerts.kml"+tt.query, nil)
+			w := httptest.NewRecorder()
+			ConcertsKML(w, r)
+			if w.Code != tt.expectedCode {
+				t.Fatalf("ConcertsKML() status code = %d, want %d", w.Code, tt.expectedCode)
+			}
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var doc kmlDocument
+			if err := xml.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+				t.Fatalf("response is not valid XML: %v", err)
+			}
+			if len(doc.Placemarks) != tt.expectedPlacemarks {
+				t.Fatalf("got %d placemarks, want %d", len(doc.Placemarks), tt.expectedPlacemarks)
+			}
+			if doc.Placemarks[0].When == "" || doc.Placemarks[0].Coordinates == "" {
+				t.Errorf("placemark is missing its date or coordinates: %+v", doc.Placemarks[0])
+			}
+		})
+	}
+}
@@ -1,26 +1,38 @@
 package server
 
 import (
+	"bytes"
 	"fmt"
-	"log"
+	"io/fs"
+	"log/slog"
 	"net/http"
-	"os"
 	"path"
-	"path/filepath"
-	"strconv"
 	"strings"
-	"text/template"
 )
 
 // renderTemplate renders a specified template with the provided data.
 func renderTemplate(w http.ResponseWriter, tmpl string, data interface{}) {
 	// Retrieve the template from the global map
-	t, ok := templates[tmpl]
+	set := currentTemplates()
+	t, ok := set.pages[tmpl]
+	if !ok && set.err != nil {
+		writeTemplateError(w, http.StatusInternalServerError, nil, set.err)
+		return
+	}
 	if !ok {
-		log.Println(tmpl, "not found")
+		slog.Error("template not found", "id", w.Header().Get(requestIDHeader), "template", tmpl)
 		ErrorPage(w, http.StatusNotFound)
 		return
 	}
+	if set.err != nil {
+		// The templates failed to reload, show the last working version under the error
+		var page bytes.Buffer
+		if err := t.ExecuteTemplate(&page, "layout.html", data); err != nil {
+			slog.Error("could not render the template", "id", w.Header().Get(requestIDHeader), "template", tmpl, "err", err)
+		}
+		writeTemplateError(w, http.StatusOK, page.Bytes(), set.err)
+		return
+	}
 	// Execute the template with the provided data and layout
 	err := t.ExecuteTemplate(w, "layout.html", data)
 	if err != nil {
@@ -29,77 +41,63 @@ func renderTemplate(w http.ResponseWriter, tmpl string, data interface{}) {
 	}
 }
 
-// checkMethodAndPath checks if the request method and path match expected values.
-func checkMethodAndPath(w http.ResponseWriter, r *http.Request, method, path string) bool {
-	// Render a 405 error page for wrong method
-	if r.Method != method {
-		ErrorPage(w, http.StatusMethodNotAllowed)
-		return false
-	}
-	// Render a 404 error page for wrong path
-	if r.URL.Path != path {
-		ErrorPage(w, http.StatusNotFound)
-		return false
-	}
-	return true
-}
-
 // MainPage serves as the home page of the application.
 func MainPage(w http.ResponseWriter, r *http.Request) {
-	if !checkMethodAndPath(w, r, http.MethodGet, "/") {
-		return
+	// The artists are still worth listing when their next shows are unknown
+	d := currentData()
+	concerts, err := d.allConcerts(r.Context())
+	if err != nil {
+		loggerFrom(r.Context()).Warn("listing the artists without their next shows", "err", err)
 	}
 	// Create a TemplateData object with the title and list of artists.
 	data := TemplateData{
-		Title: "Groupie Trackers - Artists",
-		Data:  artists,
+		Title:     "Groupie Trackers - Artists",
+		Data:      d.artists,
+		NextShows: nextShows(concerts, today()),
+		dataset:   d,
 	}
 	renderTemplate(w, "index.html", data)
 }
 
 // InfoAboutArtist serves detailed information about a specific artist.
 func InfoAboutArtist(w http.ResponseWriter, r *http.Request) {
-	if !checkMethodAndPath(w, r, http.MethodGet, "/artists/") {
-		return
-	}
-
-	// Get artist ID through query parameter and validate
-	id, err := strconv.Atoi(r.URL.Query().Get("id"))
-	if id <= 0 || id > len(artists) || err != nil {
-		log.Println(err)
-		ErrorPage(w, http.StatusBadRequest)
-		return
-	}
-	id--
-
-	// Fetch artist data
-	locations, err := FetchLocations(artists[id].Locations)
+	d := currentData()
+	artist, err := d.artistFromParam(r.PathValue("artist"))
 	if err != nil {
-		log.Println(err)
-		ErrorPage(w, http.StatusInternalServerError)
+		loggerFrom(r.Context()).Warn("unknown artist", "err", err)
+		ErrorPage(w, http.StatusNotFound)
 		return
 	}
-
-	dates, err := FetchDates(artists[id].ConcertDates)
-	if err != nil {
-		log.Println(err)
-		ErrorPage(w, http.StatusInternalServerError)
+	// IDs and differently written names lead to the canonical URL
+	if r.PathValue("artist") != d.slug(artist) {
+		http.Redirect(w, r, d.artistPath(artist.ID), http.StatusMovedPermanently)
 		return
 	}
 
-	rel, err := FetchRelation(artists[id].Relations)
+	// Fetch artist data
+	locations, dates, rel, err := d.fetchArtistDetails(r.Context(), artist)
 	if err != nil {
-		log.Println(err)
+		loggerFrom(r.Context()).Error("could not fetch the artist details", "artist", artist.Name, "err", err)
 		ErrorPage(w, http.StatusInternalServerError)
 		return
 	}
 
+	concerts := concertsOf(artist, rel)
+	upcoming, past := splitConcerts(concerts, today())
 	data := TemplateData{
 		Title:     "Artist Details",
-		Artist:    artists[id],
+		Artist:    artist,
 		Locations: locations,
 		Dates:     dates,
 		Concerts:  rel,
+		TourMap:   renderTourMap("Tour map of "+artist.Name, concerts),
+		Analytics: tourAnalytics(concerts),
+		Members:   d.bandMembers(artist),
+		Related:   d.relatedArtists(artist, Similarity, relatedLimit),
+		Timeline:  artistTimeline(artist, concerts),
+		Upcoming:  upcoming,
+		Past:      past,
+		dataset:   d,
 	}
 	// Render the artist details template with all relevant data
 	renderTemplate(w, "details.html", data)
@@ -107,10 +105,6 @@ func InfoAboutArtist(w http.ResponseWriter, r *http.Request) {
 
 // SearchPage handles the artist search functionality.
 func SearchPage(w http.ResponseWriter, r *http.Request) {
-	if !checkMethodAndPath(w, r, http.MethodGet, "/search/") {
-		return
-	}
-
 	// Get search query from URL parameters
 	query := r.URL.Query().Get("q")
 	if query == "" {
@@ -119,7 +113,8 @@ func SearchPage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var results []Artist
-	for _, artist := range artists {
+	d := currentData()
+	for _, artist := range d.artists {
 		if strings.Contains(strings.ToLower(artist.Name), strings.ToLower(query)) {
 			results = append(results, artist)
 		}
@@ -129,6 +124,7 @@ func SearchPage(w http.ResponseWriter, r *http.Request) {
 		Title:   "Search Results",
 		Query:   query,
 		Results: results,
+		dataset: d,
 	}
 
 	if len(results) == 0 {
@@ -141,6 +137,19 @@ func SearchPage(w http.ResponseWriter, r *http.Request) {
 
 // ErrorPage renders an error page based on the HTTP status code.
 func ErrorPage(w http.ResponseWriter, code int) {
+	ErrorPageDetail(w, code, "")
+}
+
+// requestError is an invalid request, with the status code to answer and an
+// explanation for ErrorPageDetail that does not repeat the user input.
+type requestError struct {
+	code   int
+	detail string
+}
+
+// ErrorPageDetail renders an error page explaining what was wrong with the request.
+// The detail is shown as is, so it must not contain user input.
+func ErrorPageDetail(w http.ResponseWriter, code int, detail string) {
 	var message string
 	switch code {
 	case http.StatusNotFound:
@@ -151,48 +160,62 @@ func ErrorPage(w http.ResponseWriter, code int) {
 		message = "Method Not Allowed"
 	case http.StatusForbidden:
 		message = "Forbidden"
+	case http.StatusServiceUnavailable:
+		message = "Service Unavailable"
 	default:
 		message = "Internal Server Error"
 	}
 	data := TemplateData{
-		Title:   "Error",
-		Status:  code,
-		Message: message,
+		Title:     "Error",
+		Status:    code,
+		Message:   message,
+		Detail:    detail,
+		RequestID: w.Header().Get(requestIDHeader),
+	}
+	basic := fmt.Sprintf("%d - %s", code, message)
+	if data.RequestID != "" {
+		basic += "\nRequest ID: " + data.RequestID
 	}
 
-	// Set HTTP response status code
-	w.WriteHeader(code)
-	tmpl, err := template.ParseFiles("templates/errors.html")
-	// Serve basic error response if template parsing fails
-	if err != nil {
-		http.Error(w, fmt.Sprintf("%d - %s", code, message), code)
+	set := currentTemplates()
+	tmpl, ok := set.pages["errors.html"]
+	// Serve basic error response if the template is not loaded
+	if !ok {
+		http.Error(w, basic, code)
 		return
 	}
-
-	// Serve basic error response if template execution fails
-	err = tmpl.Execute(w, data)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("%d - %s", code, message), code)
+	// Serve basic error response if template execution fails, which is only
+	// known once the page is rendered
+	var page bytes.Buffer
+	if err := tmpl.Execute(&page, data); err != nil {
+		slog.Error("could not render the error page", "id", data.RequestID, "err", err)
+		http.Error(w, basic, code)
+		return
+	}
+	if set.err != nil {
+		writeTemplateError(w, code, page.Bytes(), set.err)
+		return
 	}
+
+	// The headers must be set before the status code is sent
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	w.Write(page.Bytes())
 }
 
 func ServeStatic(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		ErrorPage(w, http.StatusMethodNotAllowed)
-		return
-	}
 	// Remove the /static/ prefix from the URL path
-	filePath := path.Join("static", strings.TrimPrefix(r.URL.Path, "/static/"))
+	filePath := strings.TrimPrefix(path.Clean("/"+strings.TrimPrefix(r.URL.Path, "/static/")), "/")
 
 	// Check if the file exists and is not a directory
-	info, err := os.Stat(filePath)
+	info, err := fs.Stat(staticFS, filePath)
 	if err != nil || info.IsDir() {
 		ErrorPage(w, http.StatusNotFound)
 		return
 	}
 
 	// Check the file extension
-	ext := filepath.Ext(filePath)
+	ext := path.Ext(filePath)
 	switch ext {
 	case ".css":
 		w.Header().Set("Content-Type", "text/css")
@@ -209,6 +232,13 @@ func ServeStatic(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// In development mode browsers check for edits on every request
+	if devMode {
+		w.Header().Set("Cache-Control", "no-cache")
+	} else if staticCacheTTL > 0 {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(staticCacheTTL.Seconds())))
+	}
+
 	// Serve the file
-	http.ServeFile(w, r, filePath)
+	http.ServeFileFS(w, r, staticFS, filePath)
 }
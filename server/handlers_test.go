@@ -2,10 +2,9 @@ package server
 
 import (
 	"bytes"
+	"io/fs"
 	"net/http"
 	"net/http/httptest"
-	"os"
-	"path/filepath"
 	"strings"
 	"testing"
 	"text/template"
@@ -13,9 +12,9 @@ import (
 
 func TestRenderTemplate(t *testing.T) {
 	// Mock templates
-	templates = map[string]*template.Template{
+	setTemplates(map[string]*template.Template{
 		"test.html": template.Must(template.New("layout.html").Parse("{{.Title}}")),
-	}
+	}, nil)
 
 	tests := []struct {
 		name     string
@@ -38,48 +37,7 @@ func TestRenderTemplate(t *testing.T) {
 	}
 }
 
-func TestCheckMethodAndPath(t *testing.T) {
-	tests := []struct {
-		name           string
-		method         string
-		path           string
-		expectedMethod string
-		expectedPath   string
-		expected       bool
-	}{
-		{"Correct method and path", "GET", "/test", "GET", "/test", true},
-		{"Incorrect method", "POST", "/test", "GET", "/test", false},
-		{"Incorrect path", "GET", "/wrong", "GET", "/test", false},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			w := httptest.NewRecorder()
-			r := httptest.NewRequest(tt.method, tt.path, nil)
-			result := checkMethodAndPath(w, r, tt.expectedMethod, tt.expectedPath)
-			if result != tt.expected {
-				t.Errorf("Expected %v, got %v", tt.expected, result)
-			}
-		})
-	}
-}
-
 func TestMainPage(t *testing.T) {
-	// Get the current working directory (server directory)
-	wd, err := os.Getwd()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// Move up one directory to project root
-	projectRoot := filepath.Dir(wd)
-	templateDir := filepath.Join(projectRoot, "templates")
-
-	// Verify template directory exists
-	if _, err := os.Stat(templateDir); os.IsNotExist(err) {
-		t.Fatalf("Template directory not found at %s", templateDir)
-	}
-
 	// Setup test cases
 	tests := []struct {
 		name          string
@@ -109,34 +67,15 @@ func TestMainPage(t *testing.T) {
 		},
 	}
 
-	// Create template with full path
-	layoutPath := filepath.Join(templateDir, "layout.html")
-	indexPath := filepath.Join(templateDir, "index.html")
-
-	// Verify template files exist
-	if _, err := os.Stat(layoutPath); os.IsNotExist(err) {
-		t.Fatalf("Layout template not found at %s", layoutPath)
-	}
-	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
-		t.Fatalf("Index template not found at %s", indexPath)
-	}
+	useTemplates(t, "index.html")
 
-	tmpl, err := template.ParseFiles(layoutPath, indexPath)
-	if err != nil {
-		t.Fatalf("Failed to parse templates: %v", err)
-	}
-
-	templates = map[string]*template.Template{
-		"index.html": tmpl,
-	}
-
-	// Initialize artists slice with some test data
-	artists = []Artist{
+	// Serve some test data
+	useData([]Artist{
 		{
 			ID:   1,
 			Name: "Test Artist",
 		},
-	}
+	}, nil)
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -146,8 +85,8 @@ func TestMainPage(t *testing.T) {
 			// Create response recorder
 			w := httptest.NewRecorder()
 
-			// Call the handler
-			MainPage(w, req)
+			// Serve the request through the routes
+			Routes().ServeHTTP(w, req)
 
 			// Check status code
 			if w.Code != tt.expectedCode {
@@ -171,72 +110,68 @@ func TestMainPage(t *testing.T) {
 }
 
 func TestInfoAboutArtist(t *testing.T) {
-	// Get the current working directory (server directory)
-	wd, err := os.Getwd()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// Move up one directory to project root
-	projectRoot := filepath.Dir(wd)
-	templateDir := filepath.Join(projectRoot, "templates")
-
-	// Verify template directory exists
-	if _, err := os.Stat(templateDir); os.IsNotExist(err) {
-		t.Fatalf("Template directory not found at %s", templateDir)
-	}
+	useTemplates(t, "details.html")
 
-	// Create template with full path
-	layoutPath := filepath.Join(templateDir, "layout.html")
-	detailsPath := filepath.Join(templateDir, "details.html")
-
-	// Verify template files exist
-	if _, err := os.Stat(layoutPath); os.IsNotExist(err) {
-		t.Fatalf("Layout template not found at %s", layoutPath)
-	}
-	if _, err := os.Stat(detailsPath); os.IsNotExist(err) {
-		t.Fatalf("Details template not found at %s", detailsPath)
-	}
-
-	tmpl, err := template.ParseFiles(layoutPath, detailsPath)
-	if err != nil {
-		t.Fatalf("Failed to parse templates: %v", err)
-	}
-
-	templates = map[string]*template.Template{
-		"details.html": tmpl,
-	}
-
-	// Initialize artists slice with some test data
-	artists = []Artist{
+	// Serve some test data served by a fake upstream
+	upstream := newFakeUpstream(t)
+	useUpstream(t, upstream)
+	useData([]Artist{
 		{
 			ID:           1,
 			Name:         "Test Artist",
-			Locations:    "https://groupietrackers.herokuapp.com/api/locations/1",
-			ConcertDates: "https://groupietrackers.herokuapp.com/api/dates/1",
-			Relations:    "https://groupietrackers.herokuapp.com/api/relation/1",
+			Locations:    upstream.URL + "/locations/1",
+			ConcertDates: upstream.URL + "/dates/1",
+			Relations:    upstream.URL + "/relation/1",
 		},
-	}
+	}, nil)
 
 	// Setup test cases
 	tests := []struct {
-		name           string
-		method         string
-		path           string
-		query          string
-		expectedCode   int
-		expectedTitle  string
-		expectedArtist string
+		name             string
+		method           string
+		path             string
+		query            string
+		expectedCode     int
+		expectedTitle    string
+		expectedArtist   string
+		expectedLocation string
 	}{
 		{
 			name:           "Valid GET request",
 			method:         http.MethodGet,
-			path:           "/artists/",
-			query:          "?id=1",
+			path:           "/artists/test-artist",
 			expectedCode:   http.StatusOK,
 			expectedTitle:  "Artist Details",
 			expectedArtist: "Test Artist",
 		},
+		{
+			name:             "Artist ID",
+			method:           http.MethodGet,
+			path:             "/artists/1",
+			expectedCode:     http.StatusMovedPermanently,
+			expectedLocation: "/artists/test-artist",
+		},
+		{
+			name:             "Differently written name",
+			method:           http.MethodGet,
+			path:             "/artists/Test-Artist",
+			expectedCode:     http.StatusMovedPermanently,
+			expectedLocation: "/artists/test-artist",
+		},
+		{
+			name:             "Old query URL",
+			method:           http.MethodGet,
+			path:             "/artists/",
+			query:            "?id=1",
+			expectedCode:     http.StatusMovedPermanently,
+			expectedLocation: "/artists/test-artist",
+		},
+		{
+			name:         "Unknown artist",
+			method:       http.MethodGet,
+			path:         "/artists/nobody",
+			expectedCode: http.StatusNotFound,
+		},
 		{
 			name:         "Invalid ID",
 			method:       http.MethodGet,
@@ -254,8 +189,7 @@ func TestInfoAboutArtist(t *testing.T) {
 		{
 			name:         "Wrong method",
 			method:       http.MethodPost,
-			path:         "/artists/",
-			query:        "?id=1",
+			path:         "/artists/test-artist",
 			expectedCode: http.StatusMethodNotAllowed,
 		},
 	}
@@ -268,13 +202,16 @@ func TestInfoAboutArtist(t *testing.T) {
 			// Create response recorder
 			w := httptest.NewRecorder()
 
-			// Call the handler
-			InfoAboutArtist(w, req)
+			// Serve the request through the routes
+			Routes().ServeHTTP(w, req)
 
 			// Check status code
 			if w.Code != tt.expectedCode {
 				t.Errorf("InfoAboutArtist() status code = %v, want %v", w.Code, tt.expectedCode)
 			}
+			if location := w.Header().Get("Location"); location != tt.expectedLocation {
+				t.Errorf("InfoAboutArtist() redirects to %q, want %q", location, tt.expectedLocation)
+			}
 
 			// For successful requests, check the response body
 			if tt.expectedCode == http.StatusOK {
@@ -293,44 +230,10 @@ func TestInfoAboutArtist(t *testing.T) {
 }
 
 func TestSearchPage(t *testing.T) {
-	// Get the current working directory (server directory)
-	wd, err := os.Getwd()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// Move up one directory to project root
-	projectRoot := filepath.Dir(wd)
-	templateDir := filepath.Join(projectRoot, "templates")
-
-	// Verify template directory exists
-	if _, err := os.Stat(templateDir); os.IsNotExist(err) {
-		t.Fatalf("Template directory not found at %s", templateDir)
-	}
+	useTemplates(t, "search.html")
 
-	// Create template with full path
-	layoutPath := filepath.Join(templateDir, "layout.html")
-	searchPath := filepath.Join(templateDir, "search.html")
-
-	// Verify template files exist
-	if _, err := os.Stat(layoutPath); os.IsNotExist(err) {
-		t.Fatalf("Layout template not found at %s", layoutPath)
-	}
-	if _, err := os.Stat(searchPath); os.IsNotExist(err) {
-		t.Fatalf("Search template not found at %s", searchPath)
-	}
-
-	tmpl, err := template.ParseFiles(layoutPath, searchPath)
-	if err != nil {
-		t.Fatalf("Failed to parse templates: %v", err)
-	}
-
-	templates = map[string]*template.Template{
-		"search.html": tmpl,
-	}
-
-	// Initialize artists slice with some test data
-	artists = []Artist{
+	// Serve some test data
+	useData([]Artist{
 		{
 			ID:   1,
 			Name: "Test Artist",
@@ -339,7 +242,7 @@ func TestSearchPage(t *testing.T) {
 			ID:   2,
 			Name: "Another Artist",
 		},
-	}
+	}, nil)
 
 	// Setup test cases
 	tests := []struct {
@@ -356,7 +259,7 @@ func TestSearchPage(t *testing.T) {
 		{
 			name:           "Valid search query",
 			method:         http.MethodGet,
-			path:           "/search/",
+			path:           "/search",
 			query:          "?q=test",
 			expectedCode:   http.StatusOK,
 			expectedTitle:  "Search Results",
@@ -366,7 +269,7 @@ func TestSearchPage(t *testing.T) {
 		{
 			name:            "No results found",
 			method:          http.MethodGet,
-			path:            "/search/",
+			path:            "/search",
 			query:           "?q=nonexistent",
 			expectedCode:    http.StatusOK,
 			expectedTitle:   "Search Results",
@@ -376,14 +279,21 @@ func TestSearchPage(t *testing.T) {
 		{
 			name:         "Empty query",
 			method:       http.MethodGet,
-			path:         "/search/",
+			path:         "/search",
 			query:        "",
 			expectedCode: http.StatusBadRequest,
 		},
+		{
+			name:         "Old URL",
+			method:       http.MethodGet,
+			path:         "/search/",
+			query:        "?q=test",
+			expectedCode: http.StatusMovedPermanently,
+		},
 		{
 			name:         "Wrong method",
 			method:       http.MethodPost,
-			path:         "/search/",
+			path:         "/search",
 			query:        "?q=test",
 			expectedCode: http.StatusMethodNotAllowed,
 		},
@@ -397,8 +307,8 @@ func TestSearchPage(t *testing.T) {
 			// Create response recorder
 			w := httptest.NewRecorder()
 
-			// Call the handler
-			SearchPage(w, req)
+			// Serve the request through the routes
+			Routes().ServeHTTP(w, req)
 
 			// Check status code
 			if w.Code != tt.expectedCode {
@@ -444,6 +354,7 @@ func TestErrorPage(t *testing.T) {
 		{"Method Not Allowed", http.StatusMethodNotAllowed, "405 - Method Not Allowed"},
 		{"Internal Server Error", http.StatusInternalServerError, "500 - Internal Server Error"},
 	}
+	useTemplates(t, "errors.html")
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -452,25 +363,22 @@ func TestErrorPage(t *testing.T) {
 			if w.Code != tt.code {
 				t.Errorf("Expected status code %d, got %d", tt.code, w.Code)
 			}
-			if !strings.HasPrefix(w.Body.String(), tt.expected) {
+			if !strings.Contains(w.Body.String(), "<h1>"+tt.expected+"</h1>") {
 				t.Errorf("Expected body %s, got %s,", tt.expected, w.Body.String())
 			}
 		})
 	}
+
+	// Without the template a plain text error is served
+	useTemplates(t)
+	w := httptest.NewRecorder()
+	ErrorPage(w, http.StatusNotFound)
+	if w.Code != http.StatusNotFound || !strings.HasPrefix(w.Body.String(), "404 - Not Found") {
+		t.Errorf("ErrorPage() without the template = %d %s, want the plain text error", w.Code, w.Body.String())
+	}
 }
 
 func TestServeStatic_Success(t *testing.T) {
-	err := os.Chdir("..")
-	if err != nil {
-		t.Fatalf("Could not change directory: %v", err)
-	}
-	// Ensure we change back to the original directory after the test
-	defer func() {
-		err := os.Chdir("server")
-		if err != nil {
-			t.Fatalf("Could not change back to original directory: %v", err)
-		}
-	}()
 	// Create a response recorder
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest(http.MethodGet, "/static/style.css", nil)
@@ -481,7 +389,7 @@ func TestServeStatic_Success(t *testing.T) {
 	}
 	responseBody := w.Body.Bytes()
 	// Read the expected content from the file
-	expectedContent, err := os.ReadFile("static/style.css")
+	expectedContent, err := fs.ReadFile(staticFS, "style.css")
 	if err != nil {
 		t.Fatalf("Failed to read expected content from file: %v", err)
 	}
@@ -491,17 +399,6 @@ func TestServeStatic_Success(t *testing.T) {
 }
 
 func TestServeStatic_Forbidden(t *testing.T) {
-	err := os.Chdir("..")
-	if err != nil {
-		t.Fatalf("Could not change directory: %v", err)
-	}
-	// Ensure we change back to the original directory after the test
-	defer func() {
-		err := os.Chdir("server")
-		if err != nil {
-			t.Fatalf("Could not change back to original directory: %v", err)
-		}
-	}()
 	// Create a response recorder
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest(http.MethodGet, "/static/nonexistent.txt", nil)
@@ -517,17 +414,6 @@ func TestServeStatic_Forbidden(t *testing.T) {
 }
 
 func TestServeStatic_DirectoryHandling(t *testing.T) {
-	err := os.Chdir("..")
-	if err != nil {
-		t.Fatalf("Could not change directory: %v", err)
-	}
-	// Ensure we change back to the original directory after the test
-	defer func() {
-		err := os.Chdir("server")
-		if err != nil {
-			t.Fatalf("Could not change back to original directory: %v", err)
-		}
-	}()
 	// Create a response recorder
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest(http.MethodGet, "/static/", nil)
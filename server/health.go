@@ -0,0 +1,165 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// readyMaxAge is the age of the data at which the instance stops being ready, zero for no limit.
+var readyMaxAge time.Duration
+
+// refreshStatus tells when the data was last loaded and whether the loads since then failed.
+type refreshStatus struct {
+	loadedAt  time.Time
+	source    string
+	failedAt  time.Time
+	lastError string
+}
+
+// status is replaced as a whole on every change, so that /readyz reads it without waiting.
+var status atomic.Pointer[refreshStatus]
+
+// currentStatus returns the refresh status, empty before the first load.
+func currentStatus() refreshStatus {
+	if st := status.Load(); st != nil {
+		return *st
+	}
+	return refreshStatus{}
+}
+
+// updateStatus publishes a copy of the refresh status with the change applied.
+func updateStatus(change func(st *refreshStatus)) {
+	for {
+		old := status.Load()
+		var next refreshStatus
+		if old != nil {
+			next = *old
+		}
+		change(&next)
+		if status.CompareAndSwap(old, &next) {
+			return
+		}
+	}
+}
+
+// recordRefresh remembers the outcome of a data load from the source.
+func recordRefresh(source string, err error) {
+	now := time.Now()
+	updateStatus(func(st *refreshStatus) {
+		if err != nil {
+			st.failedAt = now
+			st.lastError = err.Error()
+			return
+		}
+		*st = refreshStatus{loadedAt: now, source: source}
+	})
+}
+
+// recordSnapshot reports the data as loaded from a snapshot when it was saved,
+// keeping the failure that led to it.
+func recordSnapshot(path string, savedAt time.Time) {
+	updateStatus(func(st *refreshStatus) {
+		st.loadedAt = savedAt
+		st.source = "snapshot " + path
+	})
+}
+
+// cacheStats describes the cache of upstream responses.
+type cacheStats struct {
+	Entries  int     `json:"entries"`
+	TTL      string  `json:"ttl"`
+	Hits     int     `json:"hits"`
+	Misses   int     `json:"misses"`
+	HitRatio float64 `json:"hitRatio"`
+}
+
+// readiness is the body of /readyz.
+type readiness struct {
+	Ready       bool       `json:"ready"`
+	Problems    []string   `json:"problems,omitempty"`
+	LastRefresh string     `json:"lastRefresh,omitempty"`
+	DataAge     string     `json:"dataAge,omitempty"`
+	Source      string     `json:"source,omitempty"`
+	Artists     int        `json:"artists"`
+	Cache       cacheStats `json:"cache"`
+	Warnings    []string   `json:"warnings"`
+}
+
+// checkReadiness reports whether the instance can serve pages: the templates
+// are loaded and the artists were loaded recently enough.
+func checkReadiness() readiness {
+	st := currentStatus()
+	d := currentData()
+
+	r := readiness{
+		Ready:    true,
+		Source:   st.source,
+		Artists:  len(d.artists),
+		Warnings: []string{},
+	}
+	problem := func(format string, args ...interface{}) {
+		r.Ready = false
+		r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+	}
+
+	if len(currentTemplates().pages) == 0 {
+		problem("templates are not loaded")
+	}
+	if len(d.artists) == 0 {
+		problem("no artists are loaded")
+	}
+	if st.loadedAt.IsZero() {
+		problem("the data was never loaded")
+	} else {
+		age := time.Since(st.loadedAt)
+		r.LastRefresh = st.loadedAt.UTC().Format(time.RFC3339)
+		r.DataAge = age.Round(time.Second).String()
+		if readyMaxAge > 0 && age > readyMaxAge {
+			problem("the data is %s old, more than %s", r.DataAge, readyMaxAge)
+		}
+	}
+
+	if st.failedAt.After(st.loadedAt) {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("the last refresh failed at %s: %s", st.failedAt.UTC().Format(time.RFC3339), st.lastError))
+	}
+	if missing := d.unresolvedLocations(); len(missing) > 0 {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("%d concert locations are not in the gazetteer", len(missing)))
+	}
+	var silent []string
+	for _, artist := range d.artists {
+		if len(d.relations[artist.ID].DatesLocation) == 0 {
+			silent = append(silent, artist.Name)
+		}
+	}
+	if len(silent) > 0 {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("%s no concerts: %s", plural(len(silent), "artist has", "artists have"), strings.Join(silent, ", ")))
+	}
+
+	cacheMu.Lock()
+	r.Cache.Entries = len(responseCache)
+	cacheMu.Unlock()
+	r.Cache.TTL = cacheTTL.String()
+	r.Cache.Hits = int(cacheLookups.Value("hit"))
+	r.Cache.Misses = int(cacheLookups.Value("miss"))
+	r.Cache.HitRatio = cacheHitRatio()
+	return r
+}
+
+// Healthz answers as long as the process serves requests.
+func Healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readyz describes the state of the data, with a 503 status when the instance
+// should not get traffic.
+func Readyz(w http.ResponseWriter, r *http.Request) {
+	ready := checkReadiness()
+	code := http.StatusOK
+	if !ready.Ready {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, ready)
+}
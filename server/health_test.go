@@ -0,0 +1,153 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// resetStatus forgets the data loads recorded by other tests and restores them afterwards.
+func resetStatus(t *testing.T) {
+	t.Helper()
+	old := status.Swap(nil)
+	oldAge := readyMaxAge
+	t.Cleanup(func() {
+		status.Store(old)
+		readyMaxAge = oldAge
+	})
+}
+
+// getReadyz serves /readyz and decodes its body.
+func getReadyz(t *testing.T) (int, readiness) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	Routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var body readiness
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("/readyz is not valid JSON: %v", err)
+	}
+	return w.Code, body
+}
+
+func TestHealthz(t *testing.T) {
+	w := httptest.NewRecorder()
+	Routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"ok"`) {
+		t.Errorf("/healthz = %d %s, want 200 ok", w.Code, w.Body.String())
+	}
+}
+
+func TestHealth_DuringRefresh(t *testing.T) {
+	resetStatus(t)
+	setupConcertData()
+	useTemplates(t, "index.html")
+	recordRefresh("test", nil)
+
+	// A refresh waiting for the upstream API
+	hung := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(hung)
+		<-r.Context().Done()
+	}))
+	defer upstream.Close()
+	oldURL := artistsURL
+	defer func() { artistsURL = oldURL }()
+	artistsURL = upstream.URL + "/artists"
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		loadData(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	<-hung
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		w := httptest.NewRecorder()
+		Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s during a refresh = %d %s, want 200", path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	resetStatus(t)
+	useTemplates(t, "index.html")
+	upstream := newFakeUpstream(t)
+	useUpstream(t, upstream)
+	readyMaxAge = time.Hour
+
+	// Nothing loaded yet
+	useNoData(t)
+	code, body := getReadyz(t)
+	if code != http.StatusServiceUnavailable || body.Ready || len(body.Problems) != 2 {
+		t.Errorf("/readyz before loading = %d %+v, want 503 with two problems", code, body)
+	}
+
+	if err := LoadData(); err != nil {
+		t.Fatal(err)
+	}
+	code, body = getReadyz(t)
+	if code != http.StatusOK || !body.Ready || len(body.Problems) != 0 {
+		t.Fatalf("/readyz after loading = %d %+v, want 200 and ready", code, body)
+	}
+	if body.Artists != 1 || body.Source != upstream.URL || body.LastRefresh == "" || body.DataAge != "0s" {
+		t.Errorf("/readyz = %+v, want 1 artist from the fake upstream just loaded", body)
+	}
+	if len(body.Warnings) != 0 {
+		t.Errorf("warnings = %v, want none", body.Warnings)
+	}
+
+	// A failing refresh keeps the instance ready until the data gets too old
+	artistsURL = upstream.URL + "/missing"
+	if err := LoadData(); err == nil {
+		t.Fatal("LoadData() from a missing URL succeeded")
+	}
+	code, body = getReadyz(t)
+	if code != http.StatusOK || len(body.Warnings) != 1 || !strings.Contains(body.Warnings[0], "the last refresh failed") {
+		t.Errorf("/readyz after a failed refresh = %d %+v, want ready with a warning", code, body)
+	}
+
+	updateStatus(func(st *refreshStatus) { st.loadedAt = time.Now().Add(-2 * time.Hour) })
+	code, body = getReadyz(t)
+	if code != http.StatusServiceUnavailable || len(body.Problems) != 1 || !strings.Contains(body.Problems[0], "old") {
+		t.Errorf("/readyz with old data = %d %+v, want 503 because of the age", code, body)
+	}
+
+	// Without a limit the age does not matter
+	readyMaxAge = 0
+	if code, _ = getReadyz(t); code != http.StatusOK {
+		t.Errorf("/readyz without a maximum age = %d, want 200", code)
+	}
+}
+
+func TestCheckReadiness_Warnings(t *testing.T) {
+	resetStatus(t)
+	useTemplates(t, "index.html")
+	list, rels := concertData()
+	list = append(list, Artist{ID: 3, Name: "Silent Band"})
+	rels[1].DatesLocation["atlantis-ocean"] = []string{"01-01-2020"}
+	useData(list, rels)
+	recordRefresh("test", nil)
+	recordRefresh("test", errors.New("upstream down"))
+
+	r := checkReadiness()
+	if !r.Ready {
+		t.Fatalf("checkReadiness() = %+v, want ready", r)
+	}
+	warnings := strings.Join(r.Warnings, "\n")
+	for _, text := range []string{"upstream down", "not in the gazetteer", "1 artist has no concerts: Silent Band"} {
+		if !strings.Contains(warnings, text) {
+			t.Errorf("warnings %q don't mention %q", warnings, text)
+		}
+	}
+}
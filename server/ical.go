@@ -0,0 +1,122 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// icsLineLimit is the maximum length of a content line in octets (RFC 5545, section 3.1).
+const icsLineLimit = 75
+
+// ArtistConcertsICS serves the concerts of one artist as an iCalendar file.
+func ArtistConcertsICS(w http.ResponseWriter, r *http.Request) {
+	artist, concerts, ok := artistConcerts(w, r)
+	if !ok {
+		return
+	}
+	serveICS(w, artist.Name+" concerts", concerts)
+}
+
+// ConcertsICS serves a combined iCalendar feed for the artists selected by the filter parameters.
+func ConcertsICS(w http.ResponseWriter, r *http.Request) {
+	concerts, ok := currentData().filteredConcerts(w, r)
+	if !ok {
+		return
+	}
+	serveICS(w, "Groupie Tracker concerts", concerts)
+}
+
+// serveICS writes the calendar response headers followed by the calendar itself.
+func serveICS(w http.ResponseWriter, name string, concerts []Concert) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", slugify(name)+".ics"))
+	if err := writeICS(w, name, concerts, now()); err != nil {
+		slog.Error("could not write the calendar", "err", err)
+	}
+}
+
+// writeICS writes concerts as an RFC 5545 calendar of all-day events.
+// UIDs are derived from the artist, location and date so that re-imports update existing events.
+func writeICS(w io.Writer, name string, concerts []Concert, stamp time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(format string, args ...interface{}) {
+		bw.WriteString(foldLine(fmt.Sprintf(format, args...)))
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//Groupie Tracker//Concerts//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:%s", escapeText(name))
+	for _, c := range concerts {
+		location := c.LocationName()
+		line("BEGIN:VEVENT")
+		line("UID:%d-%s-%s@groupie-tracker", c.ArtistID, c.Location, c.Date.Format("20060102"))
+		line("DTSTAMP:%s", stamp.UTC().Format("20060102T150405Z"))
+		line("DTSTART;VALUE=DATE:%s", c.Date.Format("20060102"))
+		line("DTEND;VALUE=DATE:%s", c.Date.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY:%s", escapeText(c.Artist+" live in "+location))
+		line("LOCATION:%s", escapeText(location))
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+
+	return bw.Flush()
+}
+
+// escapeText escapes a TEXT property value (RFC 5545, section 3.3.11).
+func escapeText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+// foldLine terminates a content line with CRLF, folding it into continuation lines
+// of at most icsLineLimit octets without splitting UTF-8 sequences.
+func foldLine(s string) string {
+	var b strings.Builder
+	limit := icsLineLimit
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines begin with a space, which counts towards the limit
+		limit = icsLineLimit - 1
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// slugify lowercases a name and joins its words with hyphens, e.g. for file names.
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			dash = false
+		case b.Len() > 0 && !dash:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
@@ -0,0 +1,193 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"groupie-tracker/gazetteer"
+)
+
+// icsEvent holds the properties of a parsed VEVENT keyed by name (parameters included).
+type icsEvent map[string]string
+
+// parseICS unfolds the content lines of a calendar and returns its events.
+func parseICS(t *testing.T, body string) []icsEvent {
+	t.Helper()
+	if !strings.HasSuffix(body, "\r\n") {
+		t.Fatalf("calendar does not end with CRLF")
+	}
+
+	var lines []string
+	for _, raw := range strings.Split(strings.TrimSuffix(body, "\r\n"), "\r\n") {
+		if len(raw) > icsLineLimit {
+			t.Errorf("line longer than %d octets: %q", icsLineLimit, raw)
+		}
+		if strings.HasPrefix(raw, " ") && len(lines) > 0 {
+			lines[len(lines)-1] += raw[1:]
+			continue
+		}
+		lines = append(lines, raw)
+	}
+
+	if lines[0] != "BEGIN:VCALENDAR" || lines[len(lines)-1] != "END:VCALENDAR" {
+		t.Fatalf("calendar is not wrapped in VCALENDAR: %q ... %q", lines[0], lines[len(lines)-1])
+	}
+
+	var events []icsEvent
+	var current icsEvent
+	for _, line := range lines {
+		switch line {
+		case "BEGIN:VEVENT":
+			current = icsEvent{}
+		case "END:VEVENT":
+			events = append(events, current)
+			current = nil
+		default:
+			if current != nil {
+				name, value, _ := strings.Cut(line, ":")
+				current[name] = value
+			}
+		}
+	}
+	return events
+}
+
+// concertData returns the artists and concerts most tests use: Queen is ID 1 and Pink Floyd ID 2.
+func concertData() ([]Artist, map[int]Relation) {
+	list := []Artist{
+		{ID: 1, Name: "Queen", Members: []string{"Freddie Mercury", "Brian May"}, CreationDate: 1970, FirstAlbum: "14-12-1973"},
+		{ID: 2, Name: "Pink Floyd", Members: []string{"Roger Waters"}, CreationDate: 1965, FirstAlbum: "05-08-1967"},
+	}
+	rels := map[int]Relation{
+		1: {ID: 1, DatesLocation: map[string][]string{
+			"los_angeles-usa":    {"23-08-2019", "22-08-2019"},
+			"saint_denis-france": {"05-07-2020"},
+		}},
+		2: {ID: 2, DatesLocation: map[string][]string{
+			"london-uk": {"01-01-2021"},
+		}},
+	}
+	return list, rels
+}
+
+// useData serves the artists and their concerts as if they had been loaded.
+func useData(list []Artist, rels map[int]Relation) *dataset {
+	d := newDataset(list, rels)
+	current.Store(d)
+	dataLoaded.Store(true)
+	return d
+}
+
+// setupConcertData serves the artists and concerts of concertData.
+func setupConcertData() *dataset {
+	return useData(concertData())
+}
+
+func TestArtistConcertsICS(t *testing.T) {
+	setupConcertData()
+	places = gazetteer.Default()
+
+	r := httptest.NewRequest(http.MethodGet, "/artists/1/concerts.ics", nil)
+	r.SetPathValue("artist", "1")
+	w := httptest.NewRecorder()
+	ArtistConcertsICS(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("ArtistConcertsICS() status code = %d, want %d", w.Code, http.StatusOK)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+		t.Errorf("Content-Type = %q, want text/calendar", ct)
+	}
+
+	events := parseICS(t, w.Body.String())
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+
+	first := events[0]
+	expected := icsEvent{
+		"UID":                "1-los_angeles-usa-20190822@groupie-tracker",
+		"DTSTART;VALUE=DATE": "20190822",
+		"DTEND;VALUE=DATE":   "20190823",
+		"SUMMARY":            `Queen live in Los Angeles\, United States`,
+		"LOCATION":           `Los Angeles\, United States`,
+	}
+	for name, value := range expected {
+		if first[name] != value {
+			t.Errorf("%s = %q, want %q", name, first[name], value)
+		}
+	}
+	if _, err := time.Parse("20060102T150405Z", first["DTSTAMP"]); err != nil {
+		t.Errorf("invalid DTSTAMP %q: %v", first["DTSTAMP"], err)
+	}
+	if events[2]["UID"] != "1-saint_denis-france-20200705@groupie-tracker" {
+		t.Errorf("events are not in chronological order: last UID %q", events[2]["UID"])
+	}
+}
+
+func TestArtistConcertsICS_InvalidID(t *testing.T) {
+	setupConcertData()
+
+	for _, id := range []string{"0", "3", "abc"} {
+		r := httptest.NewRequest(http.MethodGet, "/artists/"+id+"/concerts.ics", nil)
+		r.SetPathValue("artist", id)
+		w := httptest.NewRecorder()
+		ArtistConcertsICS(w, r)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("id %q: status code = %d, want %d", id, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestConcertsICS(t *testing.T) {
+	setupConcertData()
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedCode   int
+		expectedEvents int
+	}{
+		{"All artists", "", http.StatusOK, 4},
+		{"By ID", "?ids=2", http.StatusOK, 1},
+		{"By location", "?location=france", http.StatusOK, 3},
+		{"By creation year", "?created_from=1968", http.StatusOK, 3},
+		{"No match", "?q=nobody", http.StatusOK, 0},
+		{"Invalid IDs", "?ids=1,x", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/concerts.ics"+tt.query, nil)
+			w := httptest.NewRecorder()
+			ConcertsICS(w, r)
+			if w.Code != tt.expectedCode {
+				t.Fatalf("ConcertsICS() status code = %d, want %d", w.Code, tt.expectedCode)
+			}
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+			events := parseICS(t, w.Body.String())
+			if len(events) != tt.expectedEvents {
+				t.Errorf("got %d events, want %d", len(events), tt.expectedEvents)
+			}
+		})
+	}
+}
+
+func TestFoldLine(t *testing.T) {
+	long := "SUMMARY:" + strings.Repeat("é", 80)
+	folded := foldLine(long)
+	for _, line := range strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n") {
+		if len(line) > icsLineLimit {
+			t.Errorf("folded line has %d octets", len(line))
+		}
+	}
+	unfolded := strings.ReplaceAll(strings.TrimSuffix(folded, "\r\n"), "\r\n ", "")
+	if unfolded != long {
+		t.Errorf("unfolding did not restore the original line")
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"groupie-tracker/gazetteer"
+)
+
+// LocationArtist is an artist with the dates they played at a location.
+type LocationArtist struct {
+	Artist Artist
+	Dates  []string
+}
+
+// LocationDetails lists every artist who played at a location.
+type LocationDetails struct {
+	Slug     string
+	Name     string
+	Country  string
+	Concerts int
+	Artists  []LocationArtist
+}
+
+// CountryLocations groups the locations of a country for the locations index.
+type CountryLocations struct {
+	Name      string
+	Concerts  int
+	Locations []LocationDetails
+}
+
+// locationIndex inverts the relations of all artists into the concerts played
+// at each location, keyed by normalized slug.
+func (d *dataset) locationIndex(ctx context.Context) (map[string][]Concert, error) {
+	concerts, err := d.allConcerts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string][]Concert)
+	for _, c := range concerts {
+		slug := gazetteer.Normalize(c.Location)
+		index[slug] = append(index[slug], c)
+	}
+	return index, nil
+}
+
+// newLocationDetails groups the concerts at a location by artist, in order of their first show there.
+func (d *dataset) newLocationDetails(slug string, concerts []Concert) LocationDetails {
+	details := LocationDetails{
+		Slug:     slug,
+		Name:     locationName(slug),
+		Country:  locationCountry(slug),
+		Concerts: len(concerts),
+	}
+	position := make(map[int]int)
+	for _, c := range concerts {
+		i, ok := position[c.ArtistID]
+		if !ok {
+			artist, ok := d.artistByID(c.ArtistID)
+			if !ok {
+				artist = Artist{ID: c.ArtistID, Name: c.Artist}
+			}
+			i = len(details.Artists)
+			position[c.ArtistID] = i
+			details.Artists = append(details.Artists, LocationArtist{Artist: artist})
+		}
+		details.Artists[i].Dates = append(details.Artists[i].Dates, c.Date.Format(dateLayout))
+	}
+	return details
+}
+
+// LocationsPage lists every concert location grouped by country.
+func LocationsPage(w http.ResponseWriter, r *http.Request) {
+	d := currentData()
+	index, err := d.locationIndex(r.Context())
+	if err != nil {
+		loggerFrom(r.Context()).Error("could not index the locations", "err", err)
+		ErrorPage(w, http.StatusInternalServerError)
+		return
+	}
+
+	byCountry := make(map[string]*CountryLocations)
+	for slug, concerts := range index {
+		details := d.newLocationDetails(slug, concerts)
+		country, ok := byCountry[details.Country]
+		if !ok {
+			country = &CountryLocations{Name: details.Country}
+			byCountry[details.Country] = country
+		}
+		country.Concerts += details.Concerts
+		country.Locations = append(country.Locations, details)
+	}
+
+	var countries []CountryLocations
+	for _, country := range byCountry {
+		sort.Slice(country.Locations, func(i, j int) bool {
+			return country.Locations[i].Name < country.Locations[j].Name
+		})
+		countries = append(countries, *country)
+	}
+	sort.Slice(countries, func(i, j int) bool {
+		return countries[i].Name < countries[j].Name
+	})
+
+	data := TemplateData{
+		Title:     "Concert Locations",
+		Countries: countries,
+	}
+	renderTemplate(w, "locations.html", data)
+}
+
+// LocationPage lists every artist who played at a location and when.
+func LocationPage(w http.ResponseWriter, r *http.Request) {
+	d := currentData()
+	index, err := d.locationIndex(r.Context())
+	if err != nil {
+		loggerFrom(r.Context()).Error("could not index the locations", "err", err)
+		ErrorPage(w, http.StatusInternalServerError)
+		return
+	}
+
+	slug := gazetteer.Normalize(r.PathValue("slug"))
+	concerts, ok := index[slug]
+	if !ok {
+		ErrorPage(w, http.StatusNotFound)
+		return
+	}
+
+	details := d.newLocationDetails(slug, concerts)
+	data := TemplateData{
+		Title:    "Concerts in " + details.Name,
+		Location: details,
+		dataset:  d,
+	}
+	renderTemplate(w, "location.html", data)
+}
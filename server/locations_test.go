@@ -0,0 +1,97 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"text/template"
+
+	"groupie-tracker/gazetteer"
+)
+
+// useTemplates parses the given pages from the project's templates directory
+// together with the layout and makes them the templates used by the handlers.
+func useTemplates(t *testing.T, pages ...string) {
+	t.Helper()
+	parsed := make(map[string]*template.Template)
+	for _, page := range pages {
+		tmpl, err := parsePage(page)
+		if err != nil {
+			t.Fatalf("Failed to parse templates: %v", err)
+		}
+		parsed[page] = tmpl
+	}
+	setTemplates(parsed, nil)
+}
+
+func TestLocationsPage(t *testing.T) {
+	setupConcertData()
+	places = gazetteer.Default()
+	useTemplates(t, "locations.html")
+
+	r := httptest.NewRequest(http.MethodGet, "/locations/", nil)
+	w := httptest.NewRecorder()
+	LocationsPage(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("LocationsPage() status code = %d, want %d", w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	// Countries are sorted by name, unresolved ones use the country part of the slug
+	expected := []string{"France", "United Kingdom", "United States", `href="/locations/los_angeles-usa"`, "Los Angeles, United States"}
+	last := -1
+	for _, text := range expected {
+		i := strings.Index(body, text)
+		if i < 0 {
+			t.Errorf("LocationsPage() response doesn't contain %q", text)
+			continue
+		}
+		if i < last {
+			t.Errorf("LocationsPage() response has %q out of order", text)
+		}
+		last = i
+	}
+}
+
+func TestLocationPage(t *testing.T) {
+	places = gazetteer.Default()
+	useTemplates(t, "location.html")
+	// A second artist at the same location
+	list, rels := concertData()
+	rels[2].DatesLocation["los_angeles-usa"] = []string{"01-01-2019"}
+	useData(list, rels)
+
+	tests := []struct {
+		name          string
+		slug          string
+		expectedCode  int
+		expectedTexts []string
+	}{
+		{"Known location", "los_angeles-usa", http.StatusOK, []string{"Concerts in Los Angeles, United States", "Pink Floyd", "Queen", "22-08-2019", "23-08-2019"}},
+		{"Unnormalized slug", "Los_Angeles-USA", http.StatusOK, []string{"Los Angeles, United States"}},
+		{"Unknown location", "atlantis-ocean", http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/locations/"+tt.slug, nil)
+			r.SetPathValue("slug", tt.slug)
+			w := httptest.NewRecorder()
+			LocationPage(w, r)
+			if w.Code != tt.expectedCode {
+				t.Fatalf("LocationPage() status code = %d, want %d", w.Code, tt.expectedCode)
+			}
+			body := w.Body.String()
+			for _, text := range tt.expectedTexts {
+				if !strings.Contains(body, text) {
+					t.Errorf("LocationPage() response doesn't contain %q", text)
+				}
+			}
+			// Pink Floyd played Los Angeles first
+			if tt.expectedCode == http.StatusOK && strings.Index(body, "Pink Floyd") > strings.Index(body, "Queen") {
+				t.Errorf("LocationPage() does not list artists in order of their first show")
+			}
+		})
+	}
+}
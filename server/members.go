@@ -0,0 +1,115 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// MemberDetails is a musician with the artists they belong to.
+type MemberDetails struct {
+	Name    string
+	Slug    string
+	Artists []Artist
+	AlsoIn  []Artist // the member's other artists, on an artist's details page
+}
+
+// accentFolds spells accented Latin letters without their diacritics.
+var accentFolds = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "æ", "ae",
+	"ç", "c", "è", "e", "é", "e", "ê", "e", "ë", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i", "ñ", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "œ", "oe",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ý", "y", "ÿ", "y", "ß", "ss",
+	"ł", "l", "š", "s", "ž", "z", "č", "c", "ř", "r",
+)
+
+// memberSlug returns the key identifying a musician across artists: their name
+// lowercased, without accents and punctuation, e.g. "Björk Guðmundsdóttir" and
+// "bjork gudmundsdottir" both give "bjork-gudmundsdottir".
+func memberSlug(name string) string {
+	name = strings.ReplaceAll(strings.ToLower(name), "ð", "d")
+	// Apostrophes and dots join the letters around them ("O'Brien", "J.J.")
+	name = strings.NewReplacer("'", "", "’", "", ".", "").Replace(name)
+	return slugify(accentFolds.Replace(name))
+}
+
+// memberIndex groups the members of all artists by slug, keeping the first spelling
+// of each name and the artists in list order.
+func (d *dataset) memberIndex() map[string]*MemberDetails {
+	index := make(map[string]*MemberDetails)
+	for _, artist := range d.artists {
+		for _, name := range artist.Members {
+			slug := memberSlug(name)
+			if slug == "" {
+				continue
+			}
+			member, ok := index[slug]
+			if !ok {
+				member = &MemberDetails{Name: strings.TrimSpace(name), Slug: slug}
+				index[slug] = member
+			}
+			// A name listed twice in one band is still one membership
+			if n := len(member.Artists); n == 0 || member.Artists[n-1].ID != artist.ID {
+				member.Artists = append(member.Artists, artist)
+			}
+		}
+	}
+	return index
+}
+
+// bandMembers returns the members of an artist with the other artists each of them played in.
+func (d *dataset) bandMembers(artist Artist) []MemberDetails {
+	index := d.memberIndex()
+	var members []MemberDetails
+	for _, name := range artist.Members {
+		member := MemberDetails{Name: strings.TrimSpace(name), Slug: memberSlug(name)}
+		if found, ok := index[member.Slug]; ok {
+			member.Artists = found.Artists
+			for _, other := range found.Artists {
+				if other.ID != artist.ID {
+					member.AlsoIn = append(member.AlsoIn, other)
+				}
+			}
+		}
+		members = append(members, member)
+	}
+	return members
+}
+
+// MembersPage lists the musicians who played in more than one artist.
+func MembersPage(w http.ResponseWriter, r *http.Request) {
+	var members []MemberDetails
+	for _, member := range currentData().memberIndex() {
+		if len(member.Artists) > 1 {
+			members = append(members, *member)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Slug < members[j].Slug
+	})
+
+	data := TemplateData{
+		Title:   "Musicians in Several Bands",
+		Members: members,
+	}
+	renderTemplate(w, "members.html", data)
+}
+
+// MemberPage lists every artist a musician belongs to.
+func MemberPage(w http.ResponseWriter, r *http.Request) {
+	d := currentData()
+	member, ok := d.memberIndex()[memberSlug(r.PathValue("slug"))]
+	if !ok {
+		loggerFrom(r.Context()).Warn("unknown member", "slug", r.PathValue("slug"))
+		ErrorPage(w, http.StatusNotFound)
+		return
+	}
+
+	data := TemplateData{
+		Title:   member.Name,
+		Member:  *member,
+		dataset: d,
+	}
+	renderTemplate(w, "member.html", data)
+}
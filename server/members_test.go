@@ -0,0 +1,89 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMemberSlug(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"Freddie Mercury", "freddie-mercury"},
+		{"  freddie   MERCURY ", "freddie-mercury"},
+		{"Björk Guðmundsdóttir", "bjork-gudmundsdottir"},
+		{"Sinéad O'Connor", "sinead-oconnor"},
+		{"J.J. Cale", "jj-cale"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := memberSlug(tt.name); got != tt.expected {
+			t.Errorf("memberSlug(%q) = %q, want %q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestBandMembers(t *testing.T) {
+	list, rels := concertData()
+	list[1].Members = append(list[1].Members, "Brian  MAY")
+	d := useData(list, rels)
+
+	members := d.bandMembers(list[0])
+	if len(members) != 2 {
+		t.Fatalf("bandMembers() returned %d members, want 2", len(members))
+	}
+	if len(members[0].AlsoIn) != 0 {
+		t.Errorf("Freddie Mercury also played in %v, want none", members[0].AlsoIn)
+	}
+	if len(members[1].AlsoIn) != 1 || members[1].AlsoIn[0].Name != "Pink Floyd" {
+		t.Errorf("Brian May also played in %v, want Pink Floyd", members[1].AlsoIn)
+	}
+}
+
+func TestMemberPage(t *testing.T) {
+	list, rels := concertData()
+	list[1].Members = append(list[1].Members, "Brian  MAY")
+	useData(list, rels)
+	useTemplates(t, "member.html", "members.html")
+
+	tests := []struct {
+		name          string
+		slug          string
+		expectedCode  int
+		expectedTexts []string
+	}{
+		{"Member of two artists", "brian-may", http.StatusOK, []string{"Brian May", "Member of 2 artists", `href="/artists/queen"`, `href="/artists/pink-floyd"`}},
+		{"Unnormalized slug", "Roger_Waters", http.StatusOK, []string{"Roger Waters", "Member of 1 artist"}},
+		{"Unknown member", "john-doe", http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/members/"+tt.slug, nil)
+			r.SetPathValue("slug", tt.slug)
+			w := httptest.NewRecorder()
+			MemberPage(w, r)
+			if w.Code != tt.expectedCode {
+				t.Fatalf("MemberPage() status code = %d, want %d", w.Code, tt.expectedCode)
+			}
+			body := w.Body.String()
+			for _, text := range tt.expectedTexts {
+				if !strings.Contains(body, text) {
+					t.Errorf("MemberPage() response doesn't contain %q", text)
+				}
+			}
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/members/", nil)
+	w := httptest.NewRecorder()
+	MembersPage(w, r)
+	body := w.Body.String()
+	if !strings.Contains(body, `href="/members/brian-may"`) || strings.Contains(body, "Roger Waters") {
+		t.Errorf("MembersPage() should only list Brian May, got %s", body)
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"groupie-tracker/metrics"
+)
+
+// registry holds the metrics served on /metrics.
+var registry = metrics.NewRegistry()
+
+var (
+	httpRequests = registry.NewCounter("groupie_http_requests_total",
+		"HTTP requests served, by route pattern and status code.", "route", "status")
+	httpDuration = registry.NewHistogram("groupie_http_request_duration_seconds",
+		"Time taken to serve HTTP requests, by route pattern and status code.", metrics.DefaultBuckets, "route", "status")
+	upstreamDuration = registry.NewHistogram("groupie_upstream_request_duration_seconds",
+		"Time taken by requests to the upstream API, by endpoint.", metrics.DefaultBuckets, "endpoint")
+	upstreamErrors = registry.NewCounter("groupie_upstream_errors_total",
+		"Failed requests to the upstream API, by endpoint.", "endpoint")
+	cacheLookups = registry.NewCounter("groupie_cache_lookups_total",
+		"Lookups of upstream responses in the cache, by result (hit or miss).", "result")
+	artistCount = registry.NewGauge("groupie_artists",
+		"Number of artists loaded from the upstream API.")
+	lastRefresh = registry.NewGauge("groupie_last_refresh_timestamp_seconds",
+		"Unix time of the last successful load of the artists and concerts.")
+	refreshErrors = registry.NewCounter("groupie_refresh_errors_total",
+		"Failed loads of the artists and concerts.")
+)
+
+func init() {
+	registry.NewGaugeFunc("groupie_cache_hit_ratio",
+		"Share of cache lookups answered from the cache since the start, 0 before any lookup.", cacheHitRatio)
+}
+
+// cacheHitRatio returns the share of cache lookups that were hits.
+func cacheHitRatio() float64 {
+	hits, misses := cacheLookups.Value("hit"), cacheLookups.Value("miss")
+	if hits+misses == 0 {
+		return 0
+	}
+	return hits / (hits + misses)
+}
+
+// instrument counts the requests served by a route and how long they took.
+func instrument(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &responseRecorder{ResponseWriter: w}
+		defer func() {
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			// A panicking handler ends in a 500 from Recover, which still needs the panic
+			err := recover()
+			if err != nil {
+				status = http.StatusInternalServerError
+			}
+			code := strconv.Itoa(status)
+			httpRequests.Inc(route, code)
+			httpDuration.Observe(time.Since(start).Seconds(), route, code)
+			if err != nil {
+				panic(err)
+			}
+		}()
+		next(rec, r)
+	}
+}
+
+// upstreamEndpoint names the upstream endpoint of a URL for the metrics, e.g.
+// "artists" or "locations/{id}", so that the artists do not each get a series.
+func upstreamEndpoint(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "unknown"
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	last := segments[len(segments)-1]
+	if _, err := strconv.Atoi(last); err == nil && len(segments) > 1 {
+		return segments[len(segments)-2] + "/{id}"
+	}
+	if last == "" {
+		return "unknown"
+	}
+	return last
+}
+
+// observeUpstream records the duration and the outcome of an upstream request.
+func observeUpstream(rawURL string, start time.Time, failed bool) {
+	endpoint := upstreamEndpoint(rawURL)
+	upstreamDuration.Observe(time.Since(start).Seconds(), endpoint)
+	if failed {
+		upstreamErrors.Inc(endpoint)
+	}
+}
+
+// Metrics serves the metrics in the Prometheus text format.
+func Metrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := registry.WriteText(w); err != nil {
+		loggerFrom(r.Context()).Error("could not write the metrics", "err", err)
+	}
+}
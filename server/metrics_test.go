@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpstreamEndpoint(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://groupietrackers.herokuapp.com/api/artists", "artists"},
+		{"https://groupietrackers.herokuapp.com/api/relation", "relation"},
+		{"https://groupietrackers.herokuapp.com/api/relation/12", "relation/{id}"},
+		{"http://127.0.0.1:8080/locations/3/", "locations/{id}"},
+		{"http://127.0.0.1:8080/", "unknown"},
+		{"%zz", "unknown"},
+	}
+	for _, tt := range tests {
+		if got := upstreamEndpoint(tt.url); got != tt.expected {
+			t.Errorf("upstreamEndpoint(%q) = %q, want %q", tt.url, got, tt.expected)
+		}
+	}
+}
+
+func TestRouteMetrics(t *testing.T) {
+	setupConcertData()
+	useTemplates(t, "stats.html")
+	routes := Routes()
+
+	ok := httpRequests.Value("GET /stats", "200")
+	notAllowed := httpRequests.Value("/stats", "405")
+	unmatched := httpRequests.Value("unmatched", "404")
+	observed := httpDuration.Count("GET /stats", "200")
+
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/stats"},
+		{http.MethodGet, "/stats"},
+		{http.MethodPost, "/stats"},
+		{http.MethodGet, "/nowhere/at/all"},
+	} {
+		routes.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, nil))
+	}
+
+	if got := httpRequests.Value("GET /stats", "200") - ok; got != 2 {
+		t.Errorf("GET /stats counted %v times, want 2", got)
+	}
+	if got := httpRequests.Value("/stats", "405") - notAllowed; got != 1 {
+		t.Errorf("POST /stats counted %v times as 405, want 1", got)
+	}
+	if got := httpRequests.Value("unmatched", "404") - unmatched; got != 1 {
+		t.Errorf("unknown path counted %v times, want 1", got)
+	}
+	if got := httpDuration.Count("GET /stats", "200") - observed; got != 2 {
+		t.Errorf("GET /stats latency observed %d times, want 2", got)
+	}
+}
+
+func TestRouteMetrics_Panic(t *testing.T) {
+	captureLogs(t)
+	handler := Recover(instrument("GET /boom", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	failed := httpRequests.Value("GET /boom", "500")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/boom", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("panicking route = %d, want the error page", w.Code)
+	}
+	if got := httpRequests.Value("GET /boom", "500") - failed; got != 1 {
+		t.Errorf("panicking route counted %v times as 500, want 1", got)
+	}
+	if got := httpRequests.Value("GET /boom", "200"); got != 0 {
+		t.Errorf("panicking route counted %v times as 200, want 0", got)
+	}
+}
+
+func TestUpstreamMetrics(t *testing.T) {
+	upstream := newFakeUpstream(t)
+	useUpstream(t, upstream)
+
+	observed := upstreamDuration.Count("locations/{id}")
+	failed := upstreamErrors.Value("missing")
+	refused := upstreamErrors.Value("artists")
+
+	fetchBody(context.Background(), upstream.URL+"/locations/1")
+	fetchBody(context.Background(), upstream.URL+"/missing")
+	fetchBody(context.Background(), "http://127.0.0.1:1/artists")
+
+	if got := upstreamDuration.Count("locations/{id}") - observed; got != 1 {
+		t.Errorf("locations latency observed %d times, want 1", got)
+	}
+	if got := upstreamErrors.Value("missing") - failed; got != 1 {
+		t.Errorf("404 from the upstream counted %v times, want 1", got)
+	}
+	if got := upstreamErrors.Value("artists") - refused; got != 1 {
+		t.Errorf("refused connection counted %v times, want 1", got)
+	}
+}
+
+func TestCacheMetrics(t *testing.T) {
+	upstream := newFakeUpstream(t)
+	useUpstream(t, upstream)
+	cacheTTL = time.Hour
+
+	hits, misses := cacheLookups.Value("hit"), cacheLookups.Value("miss")
+	for i := 0; i < 4; i++ {
+		FetchDates(context.Background(), upstream.URL+"/dates/1")
+	}
+	if got := cacheLookups.Value("hit") - hits; got != 3 {
+		t.Errorf("cache hits = %v, want 3", got)
+	}
+	if got := cacheLookups.Value("miss") - misses; got != 1 {
+		t.Errorf("cache misses = %v, want 1", got)
+	}
+	if ratio := cacheHitRatio(); ratio <= 0 || ratio > 1 {
+		t.Errorf("cacheHitRatio() = %v, want a share of the lookups", ratio)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	setupConcertData()
+	upstream := newFakeUpstream(t)
+	useUpstream(t, upstream)
+	before := time.Now().Unix()
+	if err := LoadData(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	Routes().ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("Metrics() = %d %q, want the Prometheus text format", w.Code, w.Header().Get("Content-Type"))
+	}
+	body := w.Body.String()
+	for _, text := range []string{
+		"# TYPE groupie_http_requests_total counter",
+		"# TYPE groupie_http_request_duration_seconds histogram",
+		`groupie_upstream_request_duration_seconds_count{endpoint="artists"}`,
+		"# TYPE groupie_upstream_errors_total counter",
+		"# TYPE groupie_cache_lookups_total counter",
+		"groupie_cache_hit_ratio ",
+		"groupie_artists 1\n",
+		"groupie_last_refresh_timestamp_seconds ",
+	} {
+		if !strings.Contains(body, text) {
+			t.Errorf("metrics don't contain %q", text)
+		}
+	}
+	if lastRefresh.Value() < float64(before) {
+		t.Errorf("last refresh = %v, want the time of LoadData", lastRefresh.Value())
+	}
+}
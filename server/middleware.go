@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// requestIDHeader carries the ID of a request, from the client or a proxy when
+// they set one and back to the client in the response.
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the longest request ID taken over from a client.
+const maxRequestIDLength = 64
+
+// Middleware wraps a handler with behaviour shared by every request.
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps the handler in the middlewares, the first one being the outermost.
+func Chain(h http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// Handler returns the routes of the site wrapped in the middlewares.
+func Handler() http.Handler {
+	return Chain(Routes(), RequestID, AccessLog, Recover, Degraded)
+}
+
+type requestIDKey struct{}
+
+// requestIDFrom returns the ID of the request the context belongs to, if any.
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// loggerFrom returns the logger for the request the context belongs to, which
+// tags every record with the request ID like the access log does.
+func loggerFrom(ctx context.Context) *slog.Logger {
+	if id := requestIDFrom(ctx); id != "" {
+		return slog.Default().With("id", id)
+	}
+	return slog.Default()
+}
+
+// validRequestID reports whether a client supplied ID is short and only made of
+// letters, digits, dashes, underscores and dots, so that it can be shown as is.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns 16 random bytes in hex.
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RequestID gives each request an ID, keeping a valid one sent in the X-Request-ID
+// header. The ID is set on the response header, where error pages find it, and in
+// the request context.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// responseRecorder remembers the status and the size of a response.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *responseRecorder) WriteHeader(code int) {
+	if rec.status == 0 {
+		rec.status = code
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Unwrap gives http.ResponseController access to the underlying writer.
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// AccessLog logs every request with its status, response size and latency,
+// including the requests aborted by a panic.
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &responseRecorder{ResponseWriter: w}
+		defer func() {
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			slog.LogAttrs(r.Context(), slog.LevelInfo, "request",
+				slog.String("id", requestIDFrom(r.Context())),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.RequestURI()),
+				slog.Int("status", status),
+				slog.Int("bytes", rec.bytes),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("remote", r.RemoteAddr),
+			)
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// Recover turns a panicking handler into a 500 error page and logs the stack.
+// When the response was already started it can only be cut short.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &responseRecorder{ResponseWriter: w}
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			// The server aborts the response on its own without logging
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			slog.Error("panic serving request",
+				"id", requestIDFrom(r.Context()),
+				"method", r.Method,
+				"path", r.URL.RequestURI(),
+				"error", err,
+				"stack", string(debug.Stack()),
+			)
+			if rec.status != 0 {
+				panic(http.ErrAbortHandler)
+			}
+			// Headers meant for the response that failed would describe the error page
+			for _, name := range []string{"Content-Type", "Content-Disposition", "Content-Length"} {
+				w.Header().Del(name)
+			}
+			ErrorPage(w, http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// captureLogs sends the slog output to a buffer until the test ends.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	old := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(old) })
+	return &buf
+}
+
+func TestChain(t *testing.T) {
+	var order []string
+	mark := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	handler := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	}), mark("first"), mark("second"))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if strings.Join(order, " ") != "first second handler" {
+		t.Errorf("Chain() ran %v, want first second handler", order)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		sent   string
+		keep   bool
+		length int
+	}{
+		{"Generated", "", false, 32},
+		{"Propagated", "abc-123_X.y", true, 11},
+		{"Markup", "<script>", false, 32},
+		{"Too long", strings.Repeat("a", maxRequestIDLength+1), false, 32},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = requestIDFrom(r.Context())
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.sent != "" {
+				r.Header.Set(requestIDHeader, tt.sent)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			id := w.Header().Get(requestIDHeader)
+			if id != seen {
+				t.Errorf("response ID %q, context ID %q, want the same", id, seen)
+			}
+			if (id == tt.sent) != tt.keep || len(id) != tt.length {
+				t.Errorf("request ID = %q for %q, want kept %v", id, tt.sent, tt.keep)
+			}
+		})
+	}
+
+	// Generated IDs differ
+	a, b := newRequestID(), newRequestID()
+	if a == b {
+		t.Errorf("newRequestID() returned %q twice", a)
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	logs := captureLogs(t)
+	handler := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short and stout"))
+	}), RequestID, AccessLog)
+
+	r := httptest.NewRequest(http.MethodGet, "/teapot?size=small", nil)
+	r.Header.Set(requestIDHeader, "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	for _, text := range []string{"msg=request", "id=req-1", "method=GET", `path="/teapot?size=small"`, "status=418", "bytes=15", "duration="} {
+		if !strings.Contains(logs.String(), text) {
+			t.Errorf("access log %q doesn't contain %q", logs.String(), text)
+		}
+	}
+}
+
+func TestLoggerFrom(t *testing.T) {
+	logs := captureLogs(t)
+	handler := Chain(http.HandlerFunc(RedirectArtistQuery), RequestID)
+
+	r := httptest.NewRequest(http.MethodGet, "/artist?id=abc", nil)
+	r.Header.Set(requestIDHeader, "req-7")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	// The errors of a handler can be told apart by the ID of its request
+	for _, text := range []string{"level=WARN", `msg="invalid artist ID"`, "id=req-7", `err="strconv.Atoi`} {
+		if !strings.Contains(logs.String(), text) {
+			t.Errorf("handler log %q doesn't contain %q", logs.String(), text)
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	logs := captureLogs(t)
+	panicking := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		panic("something broke")
+	}), RequestID, AccessLog, Recover)
+
+	r := httptest.NewRequest(http.MethodGet, "/export/artists.csv", nil)
+	r.Header.Set(requestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+	panicking.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status code = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if strings.Contains(w.Header().Get("Content-Type"), "csv") {
+		t.Errorf("error page kept the Content-Type %q of the failed response", w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Body.String(), "req-42") {
+		t.Errorf("error page doesn't show the request ID: %s", w.Body.String())
+	}
+	for _, text := range []string{"panic serving request", "something broke", "id=req-42", "middleware_test.go", "status=500"} {
+		if !strings.Contains(logs.String(), text) {
+			t.Errorf("logs don't contain %q", text)
+		}
+	}
+}
+
+func TestRecover_StartedResponse(t *testing.T) {
+	captureLogs(t)
+	handler := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		panic("too late")
+	}))
+
+	defer func() {
+		if err := recover(); err != http.ErrAbortHandler {
+			t.Errorf("Recover() panicked with %v, want http.ErrAbortHandler", err)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	t.Error("Recover() completed a response that had already started")
+}
+
+func TestErrorPage_RequestID(t *testing.T) {
+	useTemplates(t, "errors.html")
+
+	w := httptest.NewRecorder()
+	w.Header().Set(requestIDHeader, "req-7")
+	ErrorPage(w, http.StatusNotFound)
+	if !strings.Contains(w.Body.String(), "<code>req-7</code>") {
+		t.Errorf("error page doesn't show the request ID: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	ErrorPage(w, http.StatusNotFound)
+	if strings.Contains(w.Body.String(), "Request ID") {
+		t.Error("error page shows a request ID without one")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	logs := captureLogs(t)
+	setupConcertData()
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+	if w.Code != http.StatusNotFound || w.Header().Get(requestIDHeader) == "" {
+		t.Errorf("Handler() = %d with request ID %q, want a 404 with an ID", w.Code, w.Header().Get(requestIDHeader))
+	}
+	if !strings.Contains(logs.String(), "status=404") {
+		t.Errorf("Handler() did not log the request: %s", logs.String())
+	}
+}
@@ -1,5 +1,11 @@
 package server
 
+import (
+	"strconv"
+
+	"groupie-tracker/analytics"
+)
+
 // Defines the data structuresto be fetched representing artists, locations, dates, and concert relations
 type Artist struct {
 	ID           int      `json:"id"`
@@ -22,19 +28,53 @@ type Loc struct {
 }
 
 type Relation struct {
+	ID            int                 `json:"id"`
 	DatesLocation map[string][]string `json:"datesLocations"`
 }
 
+type RelationIndex struct {
+	Index []Relation `json:"index"`
+}
+
 // Passes dynamic data to HTML templates for rendering web pages.
 type TemplateData struct {
-	Title     string
-	Artist    Artist
-	Data      []Artist
-	Locations Loc
-	Dates     Date
-	Concerts  Relation
-	Query     string
-	Results   []Artist
-	Message   string
-	Status    int
+	Title      string
+	Artist     Artist
+	Data       []Artist
+	Locations  Loc
+	Dates      Date
+	Concerts   Relation
+	TourMap    string
+	Analytics  analytics.Summary
+	Location   LocationDetails
+	Countries  []CountryLocations
+	Date       DateDetails
+	Member     MemberDetails
+	Members    []MemberDetails
+	Related    []RelatedArtist
+	Comparison Comparison
+	Stats      DatasetStats
+	Timeline   Timeline
+	Calendar   CalendarMonth
+	Upcoming   []Concert
+	Past       []Concert
+	NextShows  map[int]*Concert
+	Nearby     NearbyResults
+	Query      string
+	Results    []Artist
+	Message    string
+	Detail     string
+	Status     int
+	RequestID  string
+	// dataset is the data the page was built from, which its artist links must match
+	dataset *dataset
+}
+
+// ArtistPath returns the URL of the page of the artist with the given ID, e.g.
+// {{ $.ArtistPath .ID }} in a template.
+func (t TemplateData) ArtistPath(id int) string {
+	if t.dataset == nil {
+		return "/artists/" + strconv.Itoa(id)
+	}
+	return t.dataset.artistPath(id)
 }
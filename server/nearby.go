@@ -0,0 +1,164 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"groupie-tracker/analytics"
+	"groupie-tracker/gazetteer"
+)
+
+// Radius used when /nearby is given none, and the size of a mile for radii such as "300mi".
+const (
+	defaultNearbyRadiusKm = 100
+	kmPerMile             = 1.609344
+)
+
+// NearbyQuery selects the concerts within a radius of a city and, optionally, a date window.
+type NearbyQuery struct {
+	Center   gazetteer.Place
+	RadiusKm float64
+	From     time.Time // zero for no lower bound
+	To       time.Time // zero for no upper bound, inclusive otherwise
+}
+
+// NearbyConcert is a concert with its distance from the center of the search.
+type NearbyConcert struct {
+	Concert
+	DistanceKm float64
+}
+
+// NearbyResults holds a nearby search for the page, with the submitted form values.
+type NearbyResults struct {
+	Query    NearbyQuery
+	City     string
+	Radius   string
+	From     string
+	To       string
+	Searched bool
+	Concerts []NearbyConcert
+}
+
+// parseRadius reads a distance such as "500km", "300mi" or "250" (kilometres) and returns it in kilometres.
+func parseRadius(s string) (float64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	unit := 1.0
+	switch {
+	case strings.HasSuffix(s, "km"):
+		s = strings.TrimSuffix(s, "km")
+	case strings.HasSuffix(s, "mi"):
+		s, unit = strings.TrimSuffix(s, "mi"), kmPerMile
+	}
+	radius, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(radius) || math.IsInf(radius, 0) || !(radius > 0) {
+		return 0, fmt.Errorf("invalid radius %q", s)
+	}
+	return radius * unit, nil
+}
+
+// parseNearbyQuery reads the city, radius, from and to parameters of a nearby search.
+func parseNearbyQuery(query url.Values) (NearbyQuery, *requestError) {
+	var q NearbyQuery
+	city := strings.TrimSpace(query.Get("city"))
+	if city == "" {
+		return q, &requestError{http.StatusBadRequest, "Give the city to search around as a location slug, e.g. /nearby?city=berlin-germany."}
+	}
+	place, ok := places.Lookup(city)
+	if !ok {
+		return q, &requestError{http.StatusNotFound, "The city is not in the gazetteer. Use a location slug such as berlin-germany."}
+	}
+	q.Center = place
+
+	q.RadiusKm = defaultNearbyRadiusKm
+	if radius := query.Get("radius"); radius != "" {
+		var err error
+		if q.RadiusKm, err = parseRadius(radius); err != nil {
+			return q, &requestError{http.StatusBadRequest, "The radius must be a positive distance such as 500km or 300mi."}
+		}
+	}
+
+	for _, bound := range []struct {
+		name   string
+		target *time.Time
+	}{{"from", &q.From}, {"to", &q.To}} {
+		value := query.Get(bound.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(dayLayout, value)
+		if err != nil {
+			return q, &requestError{http.StatusBadRequest, fmt.Sprintf("The %s date must be given as yyyy-mm-dd.", bound.name)}
+		}
+		*bound.target = t
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && q.To.Before(q.From) {
+		return q, &requestError{http.StatusBadRequest, "The from date must not be after the to date."}
+	}
+	return q, nil
+}
+
+// nearbyConcerts returns the concerts matching the query, closest first and then by date.
+// Concerts at locations without coordinates are left out.
+func nearbyConcerts(concerts []Concert, q NearbyQuery) []NearbyConcert {
+	var matches []NearbyConcert
+	for _, c := range concerts {
+		if (!q.From.IsZero() && c.Date.Before(q.From)) || (!q.To.IsZero() && c.Date.After(q.To)) {
+			continue
+		}
+		place, ok := places.Lookup(c.Location)
+		if !ok {
+			continue
+		}
+		if distance := analytics.Distance(q.Center, place); distance <= q.RadiusKm {
+			matches = append(matches, NearbyConcert{Concert: c, DistanceKm: distance})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].DistanceKm < matches[j].DistanceKm
+	})
+	return matches
+}
+
+// NearbyPage lists the concerts within a radius of a city, e.g.
+// /nearby?city=berlin-germany&radius=500km&from=2019-01-01&to=2019-12-31.
+// Without a city it shows the search form only.
+func NearbyPage(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	results := NearbyResults{
+		City:   query.Get("city"),
+		Radius: query.Get("radius"),
+		From:   query.Get("from"),
+		To:     query.Get("to"),
+	}
+	d := currentData()
+	if results.City != "" {
+		q, bad := parseNearbyQuery(query)
+		if bad != nil {
+			loggerFrom(r.Context()).Warn("invalid nearby search", "detail", bad.detail)
+			ErrorPageDetail(w, bad.code, bad.detail)
+			return
+		}
+		concerts, err := d.allConcerts(r.Context())
+		if err != nil {
+			loggerFrom(r.Context()).Error("could not list the concerts", "err", err)
+			ErrorPage(w, http.StatusInternalServerError)
+			return
+		}
+		results.Query = q
+		results.Searched = true
+		results.Concerts = nearbyConcerts(concerts, q)
+	}
+
+	data := TemplateData{
+		Title:   "Nearby Concerts",
+		Nearby:  results,
+		dataset: d,
+	}
+	renderTemplate(w, "nearby.html", data)
+}
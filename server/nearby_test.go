@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"groupie-tracker/gazetteer"
+)
+
+// setupNearbyData loads a small dataset of concerts around Berlin.
+func setupNearbyData() {
+	places = gazetteer.Default()
+	useData([]Artist{
+		{ID: 1, Name: "Kraftwerk"},
+		{ID: 2, Name: "Rammstein"},
+	}, map[int]Relation{
+		1: {ID: 1, DatesLocation: map[string][]string{
+			"berlin-germany":  {"01-06-2019"},
+			"munich-germany":  {"02-06-2019"},
+			"paris-france":    {"03-06-2019"},
+			"atlantis-ocean":  {"04-06-2019"},
+			"leipzig-germany": {"05-06-2018"},
+		}},
+		2: {ID: 2, DatesLocation: map[string][]string{
+			"hamburg-germany": {"10-06-2019", "*11-06-2019"},
+		}},
+	})
+}
+
+func TestParseRadius(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+		valid    bool
+	}{
+		{"500km", 500, true},
+		{" 250 KM ", 250, true},
+		{"100", 100, true},
+		{"10mi", 16.09344, true},
+		{"0km", 0, false},
+		{"-5", 0, false},
+		{"far", 0, false},
+		{"nan", 0, false},
+		{"NaNkm", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, err := parseRadius(tt.input)
+		if (err == nil) != tt.valid {
+			t.Errorf("parseRadius(%q) error = %v, want valid %v", tt.input, err, tt.valid)
+			continue
+		}
+		if math.Abs(got-tt.expected) > 1e-9 {
+			t.Errorf("parseRadius(%q) = %v, want %v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestNearbyConcerts(t *testing.T) {
+	setupNearbyData()
+	concerts, err := currentData().allConcerts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+		code     int
+	}{
+		{"Default radius", "city=berlin-germany", []string{"berlin-germany"}, 0},
+		{"Radius sorted by distance", "city=Berlin-Germany&radius=500km", []string{"berlin-germany", "leipzig-germany", "hamburg-germany", "hamburg-germany"}, 0},
+		{"Date window", "city=berlin-germany&radius=600km&from=2019-06-02&to=2019-06-10", []string{"hamburg-germany", "munich-germany"}, 0},
+		{"Everything", "city=berlin-germany&radius=10000km", []string{"berlin-germany", "leipzig-germany", "hamburg-germany", "hamburg-germany", "munich-germany", "paris-france"}, 0},
+		{"Missing city", "radius=500km", nil, http.StatusBadRequest},
+		{"Unknown city", "city=atlantis-ocean", nil, http.StatusNotFound},
+		{"Invalid radius", "city=berlin-germany&radius=far", nil, http.StatusBadRequest},
+		{"Invalid date", "city=berlin-germany&from=01-06-2019", nil, http.StatusBadRequest},
+		{"Reversed window", "city=berlin-germany&from=2019-06-10&to=2019-06-01", nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+			q, bad := parseNearbyQuery(query)
+			if tt.code != 0 {
+				if bad == nil || bad.code != tt.code {
+					t.Fatalf("parseNearbyQuery(%q) = %+v, want status %d", tt.query, bad, tt.code)
+				}
+				return
+			}
+			if bad != nil {
+				t.Fatalf("parseNearbyQuery(%q) error = %s", tt.query, bad.detail)
+			}
+
+			var got []string
+			for _, c := range nearbyConcerts(concerts, q) {
+				got = append(got, c.Location)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.expected, " ") {
+				t.Errorf("nearbyConcerts() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNearbyPage(t *testing.T) {
+	setupNearbyData()
+	useTemplates(t, "nearby.html")
+
+	r := httptest.NewRequest(http.MethodGet, "/nearby?city=berlin-germany&radius=300km&from=2019-01-01", nil)
+	w := httptest.NewRecorder()
+	NearbyPage(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("NearbyPage() status code = %d, want %d", w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	for _, text := range []string{"within 300 km of", "Berlin, Germany", "0 km", "Rammstein", "Hamburg, Germany"} {
+		if !strings.Contains(body, text) {
+			t.Errorf("NearbyPage() response doesn't contain %q", text)
+		}
+	}
+	if strings.Contains(body, "Leipzig") {
+		t.Errorf("NearbyPage() lists a concert before the from date")
+	}
+
+	// The form alone, without a search
+	r = httptest.NewRequest(http.MethodGet, "/nearby", nil)
+	w = httptest.NewRecorder()
+	NearbyPage(w, r)
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "No concerts found") {
+		t.Errorf("NearbyPage() without a city = %d, want the empty form", w.Code)
+	}
+}
+
+func TestAPINearby(t *testing.T) {
+	setupNearbyData()
+
+	r := httptest.NewRequest(http.MethodGet, "/api/nearby?city=berlin-germany&radius=300km&to=2019-06-10", nil)
+	w := httptest.NewRecorder()
+	APINearby(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("APINearby() status code = %d, want %d", w.Code, http.StatusOK)
+	}
+	var data apiNearby
+	if err := json.Unmarshal(w.Body.Bytes(), &data); err != nil {
+		t.Fatalf("response is not valid JSON: %v", err)
+	}
+	if data.City.Slug != "berlin-germany" || data.RadiusKm != 300 || data.From != "" || data.To != "2019-06-10" {
+		t.Errorf("search = %+v, want 300 km around Berlin until 2019-06-10", data)
+	}
+	if len(data.Concerts) != 3 {
+		t.Fatalf("got %d concerts, want 3", len(data.Concerts))
+	}
+	hamburg := data.Concerts[2]
+	if hamburg.Artist != "Rammstein" || hamburg.Date != "2019-06-10" || hamburg.DistanceKm < 250 || hamburg.DistanceKm > 260 {
+		t.Errorf("last concert = %+v, want Rammstein in Hamburg about 255 km away", hamburg)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/api/nearby?city=atlantis-ocean", nil)
+	w = httptest.NewRecorder()
+	APINearby(w, r)
+	var body apiErrorBody
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusNotFound || !strings.Contains(body.Error, "gazetteer") {
+		t.Errorf("APINearby() for an unknown city = %d %s, want 404 with an explanation", w.Code, w.Body.String())
+	}
+}
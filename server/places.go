@@ -0,0 +1,100 @@
+package server
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"groupie-tracker/gazetteer"
+)
+
+// gazetteerOverrides is the local file fixing locations missing from the bundled gazetteer.
+var gazetteerOverrides = "data/gazetteer_overrides.csv"
+
+// places resolves location slugs to cities, countries and coordinates.
+var places = gazetteer.Default()
+
+// loadPlaces loads the gazetteer with the local overrides applied.
+func loadPlaces() error {
+	g, err := gazetteer.Load(gazetteerOverrides)
+	if err != nil {
+		return err
+	}
+	places = g
+	return nil
+}
+
+// locationName returns the display name of a location slug, e.g. "Los Angeles, United States",
+// falling back to the formatted slug when the gazetteer does not know it.
+func locationName(slug string) string {
+	if place, ok := places.Lookup(slug); ok {
+		return place.City + ", " + place.CountryName()
+	}
+	return formatLocation(slug)
+}
+
+// locationCountry returns the country name of a location slug.
+func locationCountry(slug string) string {
+	if place, ok := places.Lookup(slug); ok {
+		return place.CountryName()
+	}
+	_, country, _ := strings.Cut(slug, "-")
+	return formatCountry(country)
+}
+
+// concertLocations returns every location slug found in the loaded relations
+// together with the names of the artists who played there.
+func (d *dataset) concertLocations() map[string][]string {
+	locations := make(map[string][]string)
+	for _, artist := range d.artists {
+		for location := range d.relations[artist.ID].DatesLocation {
+			locations[location] = append(locations[location], artist.Name)
+		}
+	}
+	return locations
+}
+
+// unresolvedLocations returns the concert locations the gazetteer cannot resolve.
+func (d *dataset) unresolvedLocations() []string {
+	var slugs []string
+	for location := range d.concertLocations() {
+		slugs = append(slugs, location)
+	}
+	return places.Unresolved(slugs)
+}
+
+// WriteGazetteerReport writes the concert locations the gazetteer cannot resolve as
+// override rows, ready to be completed and copied into the override file.
+func WriteGazetteerReport(w io.Writer) error {
+	d := currentData()
+	locations := d.concertLocations()
+	missing := d.unresolvedLocations()
+
+	fmt.Fprintf(w, "# %d of %d concert locations are not in the gazetteer.\n", len(missing), len(locations))
+	if len(missing) == 0 {
+		return nil
+	}
+	fmt.Fprintf(w, "# Complete the rows below and add them to %s.\n", gazetteerOverrides)
+
+	cw := csv.NewWriter(w)
+	cw.Write(gazetteer.Header)
+	for _, slug := range missing {
+		// The original slug is needed to find the artists, the report shows it normalized
+		var playedBy []string
+		for location, names := range locations {
+			if gazetteer.Normalize(location) == slug {
+				playedBy = append(playedBy, names...)
+			}
+		}
+		sort.Strings(playedBy)
+		cw.Flush()
+		fmt.Fprintf(w, "# played by %s\n", strings.Join(playedBy, ", "))
+
+		city, _, _ := strings.Cut(slug, "-")
+		cw.Write([]string{slug, titleWords(city), "", "", "", "", ""})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"groupie-tracker/gazetteer"
+)
+
+func TestUnresolvedLocations(t *testing.T) {
+	d := setupConcertData()
+	places = gazetteer.Default()
+
+	missing := d.unresolvedLocations()
+	if len(missing) != 1 || missing[0] != "saint_denis-france" {
+		t.Errorf("unresolvedLocations() = %v, want [saint_denis-france]", missing)
+	}
+}
+
+func TestWriteGazetteerReport(t *testing.T) {
+	setupConcertData()
+	places = gazetteer.Default()
+
+	var b strings.Builder
+	if err := WriteGazetteerReport(&b); err != nil {
+		t.Fatalf("WriteGazetteerReport() error = %v", err)
+	}
+	report := b.String()
+
+	expected := []string{
+		"# 1 of 3 concert locations are not in the gazetteer.",
+		"slug,city,country,continent,lat,lon,timezone",
+		"# played by Queen",
+		"saint_denis-france,Saint Denis,,,,,",
+	}
+	for _, line := range expected {
+		if !strings.Contains(report, line+"\n") {
+			t.Errorf("report does not contain %q:\n%s", line, report)
+		}
+	}
+}
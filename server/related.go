@@ -0,0 +1,178 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"groupie-tracker/config"
+	"groupie-tracker/gazetteer"
+)
+
+// SimilarityWeights sets how much each kind of resemblance counts when ranking related artists.
+type SimilarityWeights = config.SimilarityWeights
+
+// DefaultSimilarityWeights returns the weights used unless the configuration sets others.
+func DefaultSimilarityWeights() SimilarityWeights {
+	return config.Default().Similarity
+}
+
+// Similarity holds the weights used to rank related artists.
+var Similarity = DefaultSimilarityWeights()
+
+// relatedLimit is the number of related artists shown on an artist page.
+const relatedLimit = 5
+
+// RelatedArtist is an artist similar to another one, with the reasons why.
+type RelatedArtist struct {
+	Artist  Artist
+	Score   float64
+	Reasons []string
+}
+
+// similarityReason is one part of a similarity score.
+type similarityReason struct {
+	score float64
+	text  string
+}
+
+// artistProfile holds what the similarity of two artists is computed from.
+type artistProfile struct {
+	cities     map[string]bool
+	firstAlbum int // 0 when the date is invalid
+	members    map[string]bool
+}
+
+func (d *dataset) newArtistProfile(artist Artist) artistProfile {
+	profile := artistProfile{
+		cities:  make(map[string]bool),
+		members: make(map[string]bool),
+	}
+	for location := range d.relations[artist.ID].DatesLocation {
+		profile.cities[gazetteer.Normalize(location)] = true
+	}
+	if t, err := time.Parse(dateLayout, artist.FirstAlbum); err == nil {
+		profile.firstAlbum = t.Year()
+	}
+	for _, name := range artist.Members {
+		profile.members[memberSlug(name)] = true
+	}
+	return profile
+}
+
+// yearScore returns 1 for the same year, falling linearly to 0 at the year window.
+func yearScore(a, b, window int) float64 {
+	if window <= 0 {
+		return 0
+	}
+	diff := math.Abs(float64(a - b))
+	return math.Max(0, 1-diff/float64(window))
+}
+
+// yearsApart describes the distance between two years, e.g. "3 years apart".
+func yearsApart(a, b int) string {
+	switch diff := int(math.Abs(float64(a - b))); diff {
+	case 0:
+		return "the same year"
+	case 1:
+		return "a year apart"
+	default:
+		return fmt.Sprintf("%d years apart", diff)
+	}
+}
+
+// plural returns "1 city" or "n cities" style counts.
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return "1 " + one
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
+
+// similarity scores how alike two artists are and explains the score, biggest reasons first.
+func similarity(a, b Artist, pa, pb artistProfile, weights SimilarityWeights) (float64, []string) {
+	var reasons []similarityReason
+
+	shared := 0
+	for city := range pa.cities {
+		if pb.cities[city] {
+			shared++
+		}
+	}
+	if shared > 0 && weights.SharedCity > 0 {
+		text := fmt.Sprintf("played %d of the same cities", shared)
+		if shared == 1 {
+			text = "played one of the same cities"
+		}
+		reasons = append(reasons, similarityReason{float64(shared) * weights.SharedCity, text})
+	}
+
+	var sharedMembers []string
+	for _, name := range b.Members {
+		if pa.members[memberSlug(name)] {
+			sharedMembers = append(sharedMembers, name)
+		}
+	}
+	if len(sharedMembers) > 0 && weights.SharedMember > 0 {
+		text := "shares " + sharedMembers[0]
+		if len(sharedMembers) > 1 {
+			text = "shares " + plural(len(sharedMembers), "member", "members")
+		}
+		reasons = append(reasons, similarityReason{float64(len(sharedMembers)) * weights.SharedMember, text})
+	}
+
+	if score := yearScore(a.CreationDate, b.CreationDate, weights.YearWindow) * weights.CreationYear; score > 0 {
+		reasons = append(reasons, similarityReason{score, "formed " + yearsApart(a.CreationDate, b.CreationDate)})
+	}
+
+	if pa.firstAlbum != 0 && pb.firstAlbum != 0 {
+		if score := yearScore(pa.firstAlbum, pb.firstAlbum, weights.YearWindow) * weights.FirstAlbumYear; score > 0 {
+			reasons = append(reasons, similarityReason{score, "first albums " + yearsApart(pa.firstAlbum, pb.firstAlbum)})
+		}
+	}
+
+	// Line-ups of one and two members are as different as two and five
+	if na, nb := len(a.Members), len(b.Members); na > 0 && nb > 0 {
+		if score := math.Max(0, 1-math.Abs(float64(na-nb))/3) * weights.MemberCount; score > 0 {
+			text := fmt.Sprintf("%d and %d members", na, nb)
+			if na == nb {
+				text = "both have " + plural(na, "member", "members")
+			}
+			reasons = append(reasons, similarityReason{score, text})
+		}
+	}
+
+	sort.SliceStable(reasons, func(i, j int) bool {
+		return reasons[i].score > reasons[j].score
+	})
+	var total float64
+	texts := make([]string, len(reasons))
+	for i, reason := range reasons {
+		total += reason.score
+		texts[i] = reason.text
+	}
+	return total, texts
+}
+
+// relatedArtists returns the artists most similar to the given one, most similar first.
+func (d *dataset) relatedArtists(artist Artist, weights SimilarityWeights, limit int) []RelatedArtist {
+	profile := d.newArtistProfile(artist)
+	var related []RelatedArtist
+	for _, other := range d.artists {
+		if other.ID == artist.ID {
+			continue
+		}
+		score, reasons := similarity(artist, other, profile, d.newArtistProfile(other), weights)
+		if score > 0 {
+			related = append(related, RelatedArtist{Artist: other, Score: score, Reasons: reasons})
+		}
+	}
+	sort.SliceStable(related, func(i, j int) bool {
+		return related[i].Score > related[j].Score
+	})
+	if len(related) > limit {
+		related = related[:limit]
+	}
+	return related
+}
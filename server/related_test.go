@@ -0,0 +1,42 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRelatedArtists(t *testing.T) {
+	list, rels := concertData()
+	list = append(list, Artist{ID: 3, Name: "Queen Tribute", Members: []string{"Brian May", "Roger Taylor"}, CreationDate: 1970, FirstAlbum: "not a date"})
+	rels[3] = Relation{ID: 3, DatesLocation: map[string][]string{"Los_Angeles-USA": {"01-01-2022"}}}
+	d := useData(list, rels)
+
+	related := d.relatedArtists(list[0], DefaultSimilarityWeights(), relatedLimit)
+	if len(related) != 2 {
+		t.Fatalf("relatedArtists() returned %d artists, want 2", len(related))
+	}
+	if related[0].Artist.ID != 3 {
+		t.Errorf("most related artist = %s, want Queen Tribute", related[0].Artist.Name)
+	}
+	// Shared member 5, formed the same year 3, one city 1, same member count 1
+	if related[0].Score != 10 {
+		t.Errorf("score = %v, want 10", related[0].Score)
+	}
+	expected := []string{"shares Brian May", "formed the same year", "played one of the same cities", "both have 2 members"}
+	if !reflect.DeepEqual(related[0].Reasons, expected) {
+		t.Errorf("reasons = %q, want %q", related[0].Reasons, expected)
+	}
+
+	// Pink Floyd: formed 5 years apart, first albums 6 years apart, 2 and 1 members
+	expected = []string{"formed 5 years apart", "first albums 6 years apart", "2 and 1 members"}
+	if !reflect.DeepEqual(related[1].Reasons, expected) {
+		t.Errorf("reasons = %q, want %q", related[1].Reasons, expected)
+	}
+
+	if got := d.relatedArtists(list[0], DefaultSimilarityWeights(), 1); len(got) != 1 {
+		t.Errorf("relatedArtists() with limit 1 returned %d artists", len(got))
+	}
+	if got := d.relatedArtists(list[0], SimilarityWeights{}, relatedLimit); len(got) != 0 {
+		t.Errorf("relatedArtists() with zero weights returned %d artists, want none", len(got))
+	}
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"text/template"
+	"time"
+)
+
+// reloadInterval is how often WatchTemplates looks for changed templates.
+var reloadInterval = 500 * time.Millisecond
+
+// templateSet is the templates in use, replaced as a whole when they are reloaded.
+type templateSet struct {
+	pages map[string]*template.Template
+	// err is the failure of the last reload, shown over the pages until it is fixed
+	err error
+}
+
+// currentTemplates returns the templates in use, none before they are loaded.
+func currentTemplates() *templateSet {
+	if set := templates.Load(); set != nil {
+		return set
+	}
+	return &templateSet{}
+}
+
+// setTemplates switches to the pages, or keeps the current ones when err tells
+// that the templates could not be loaded.
+func setTemplates(pages map[string]*template.Template, err error) {
+	if err != nil {
+		pages = currentTemplates().pages
+	}
+	templates.Store(&templateSet{pages: pages, err: err})
+}
+
+// templatesVersion describes the templates on disk by the name, size and
+// modification time of each file, which changes whenever one is edited.
+func templatesVersion() (string, error) {
+	entries, err := fs.ReadDir(templatesFS, ".")
+	if err != nil {
+		return "", err
+	}
+	var version bytes.Buffer
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&version, "%s %d %d\n", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return version.String(), nil
+}
+
+// WatchTemplates reloads the templates whenever they change on disk, until the
+// context is done. A template that does not parse leaves the previous ones in
+// use, with the error shown on every page until it is fixed.
+func WatchTemplates(ctx context.Context) {
+	// The first check reloads them, catching the edits made since they were loaded
+	var last string
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		version, err := templatesVersion()
+		if err != nil {
+			slog.Error("could not watch the templates", "err", err)
+			continue
+		}
+		if version == last {
+			continue
+		}
+		first := last == ""
+		last = version
+		pages, err := loadTemplates()
+		setTemplates(pages, err)
+		if err != nil {
+			slog.Error("could not reload the templates", "err", err)
+		} else if !first {
+			slog.Info("templates reloaded")
+		}
+	}
+}
+
+// writeTemplateError serves the page rendered with the last working templates
+// with the status code, and the error of the broken ones in an overlay on top.
+// Without a page there is only the error, served as a 500.
+func writeTemplateError(w http.ResponseWriter, code int, page []byte, err error) {
+	overlay := fmt.Sprintf(`<div style="position:fixed;inset:0;z-index:9999;overflow:auto;padding:2em;`+
+		`background:rgba(20,20,20,.92);color:#ff8080;font:14px/1.5 monospace;white-space:pre-wrap">`+
+		`<strong>Template error</strong>`+"\n\n%s\n\n"+
+		`<span style="color:#ccc">Fix the template and reload the page, the last working version is underneath.</span></div>`,
+		html.EscapeString(err.Error()))
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if page == nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "<!DOCTYPE html>\n<html><head><title>Template error</title></head><body>%s</body></html>\n", overlay)
+		return
+	}
+	w.WriteHeader(code)
+	// The overlay goes at the end of the body so that it covers the page
+	if i := bytes.LastIndex(page, []byte("</body>")); i >= 0 {
+		w.Write(page[:i])
+		w.Write([]byte(overlay))
+		w.Write(page[i:])
+		return
+	}
+	w.Write(page)
+	w.Write([]byte(overlay))
+}
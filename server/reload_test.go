@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+// useTemplatesDir reads the templates from a temporary directory holding the
+// given files, and restores the previous templates after the test.
+func useTemplatesDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldFS, oldSet := templatesFS, templates.Load()
+	t.Cleanup(func() {
+		templatesFS = oldFS
+		templates.Store(oldSet)
+	})
+	templatesFS = os.DirFS(dir)
+	return dir
+}
+
+// writeTemplate replaces a template file, moving its modification time forward
+// so that the change shows even on file systems with coarse timestamps.
+func writeTemplate(t *testing.T, path, content string, version int) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(time.Duration(version) * time.Second)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitFor polls the condition until it holds or a few seconds passed.
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// render serves a page with the templates in use.
+func render(page string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	renderTemplate(w, page, TemplateData{Title: "Watched"})
+	return w
+}
+
+func TestWatchTemplates(t *testing.T) {
+	dir := useTemplatesDir(t, map[string]string{
+		"layout.html": `<html><body>{{ block "content" . }}{{ end }}</body></html>`,
+		"page.html":   `{{ define "content" }}first {{ .Title }}{{ end }}`,
+	})
+	oldInterval := reloadInterval
+	defer func() { reloadInterval = oldInterval }()
+	reloadInterval = time.Millisecond
+
+	pages, err := loadTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	setTemplates(pages, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		WatchTemplates(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// An edited template is used without a restart
+	page := filepath.Join(dir, "page.html")
+	writeTemplate(t, page, `{{ define "content" }}second {{ .Title }}{{ end }}`, 1)
+	waitFor(t, "the edited template", func() bool {
+		return strings.Contains(render("page.html").Body.String(), "second Watched")
+	})
+
+	// A broken template keeps the last working one under an error overlay
+	writeTemplate(t, page, `{{ define "content" }}third {{ .Title }`, 2)
+	waitFor(t, "the template error", func() bool { return currentTemplates().err != nil })
+	w := render("page.html")
+	body := w.Body.String()
+	if w.Code != http.StatusOK || !strings.Contains(body, "second Watched") || !strings.Contains(body, "Template error") {
+		t.Errorf("page with a broken template = %d %s, want the last version with the error", w.Code, body)
+	}
+	if !strings.Contains(body, "page.html") || strings.Index(body, "Template error") > strings.Index(body, "</body>") {
+		t.Errorf("overlay = %s, want the error about page.html inside the body", body)
+	}
+
+	// Fixing it removes the overlay
+	writeTemplate(t, page, `{{ define "content" }}fixed {{ .Title }}{{ end }}`, 3)
+	waitFor(t, "the fixed template", func() bool { return currentTemplates().err == nil })
+	if body := render("page.html").Body.String(); !strings.Contains(body, "fixed Watched") || strings.Contains(body, "Template error") {
+		t.Errorf("page after the fix = %s, want the fixed template alone", body)
+	}
+}
+
+func TestWriteTemplateError(t *testing.T) {
+	err := errors.New(`template: page.html:1: unexpected "<" in command`)
+
+	// Without a working version there is only the error to show
+	w := httptest.NewRecorder()
+	writeTemplateError(w, http.StatusOK, nil, err)
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "unexpected &#34;&lt;&#34; in command") {
+		t.Errorf("writeTemplateError() = %d %s, want a 500 with the escaped error", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	writeTemplateError(w, http.StatusOK, []byte("<html><body><p>page</p></body></html>"), err)
+	body := w.Body.String()
+	if !strings.HasPrefix(body, "<html><body><p>page</p><div") || !strings.HasSuffix(body, "</div></body></html>") {
+		t.Errorf("writeTemplateError() = %s, want the overlay at the end of the body", body)
+	}
+}
+
+func TestErrorPage_TemplateError(t *testing.T) {
+	useTemplatesDir(t, nil)
+	setTemplates(map[string]*template.Template{
+		"errors.html": template.Must(template.New("errors.html").Parse("<body>{{ .Status }}</body>")),
+	}, nil)
+	setTemplates(nil, errors.New("page.html is broken"))
+
+	w := httptest.NewRecorder()
+	ErrorPage(w, http.StatusNotFound)
+	if w.Code != http.StatusNotFound || !strings.HasPrefix(w.Body.String(), "<body>404<div") {
+		t.Errorf("error page with a broken template = %d %s, want the error page with the overlay", w.Code, w.Body.String())
+	}
+	// The headers sent are those set before the status code
+	if got := w.Result().Header.Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type of the error page with a broken template = %q, want HTML", got)
+	}
+}
+
+func TestLoad_DevMode(t *testing.T) {
+	useTemplatesDir(t, map[string]string{
+		"layout.html": `{{ block "content" . }}{{ end }}`,
+		"page.html":   `{{ define "content" }}{{ .Title }`,
+	})
+	setTemplates(nil, nil)
+	oldDev := devMode
+	defer func() { devMode = oldDev }()
+
+	devMode = false
+	if err := Load(); err == nil {
+		t.Fatal("Load() with a broken template succeeded")
+	}
+
+	// Development mode keeps serving so that the template can be fixed
+	devMode = true
+	oldOverrides := gazetteerOverrides
+	defer func() { gazetteerOverrides = oldOverrides }()
+	gazetteerOverrides = ""
+	useNoData(t)
+	useUpstream(t, newFakeUpstream(t))
+	useSnapshot(t)
+	if err := Load(); err != nil {
+		t.Fatalf("Load() in development mode = %v, want the error on the pages", err)
+	}
+	if w := render("page.html"); w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "Template error") {
+		t.Errorf("page with a broken template at startup = %d %s, want the error", w.Code, w.Body.String())
+	}
+}
+
+func TestServeStatic_DevMode(t *testing.T) {
+	oldDev := devMode
+	defer func() { devMode = oldDev }()
+	devMode = true
+
+	w := httptest.NewRecorder()
+	ServeStatic(w, httptest.NewRequest(http.MethodGet, "/static/style.css", nil))
+	if got := w.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("Cache-Control in development mode = %q, want no-cache", got)
+	}
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// route is a handler with the method and the path pattern it serves.
+type route struct {
+	method  string
+	pattern string
+	handler http.HandlerFunc
+}
+
+// routes lists every page, file and API of the site. GET routes also answer HEAD requests.
+var routes = []route{
+	{http.MethodGet, "/{$}", MainPage},
+	{http.MethodGet, "/static/", ServeStatic},
+	{http.MethodGet, "/artists/{$}", RedirectArtistQuery},
+	{http.MethodGet, "/artists/{artist}", InfoAboutArtist},
+	{http.MethodGet, "/artists/{artist}/concerts.ics", ArtistConcertsICS},
+	{http.MethodGet, "/artists/{artist}/concerts.geojson", ArtistConcertsGeoJSON},
+	{http.MethodGet, "/artists/{artist}/concerts.kml", ArtistConcertsKML},
+	{http.MethodGet, "/search", SearchPage},
+	{http.MethodGet, "/search/{$}", RedirectSearch},
+	{http.MethodGet, "/concerts.ics", ConcertsICS},
+	{http.MethodGet, "/concerts.geojson", ConcertsGeoJSON},
+	{http.MethodGet, "/concerts.kml", ConcertsKML},
+	{http.MethodGet, "/export/{name}", Export},
+	{http.MethodGet, "/api/artists", APIArtists},
+	{http.MethodGet, "/api/artists/{id}", APIArtist},
+	{http.MethodGet, "/api/nearby", APINearby},
+	{http.MethodGet, "/locations/{$}", LocationsPage},
+	{http.MethodGet, "/locations/{slug}", LocationPage},
+	{http.MethodGet, "/dates/{$}", DatesPage},
+	{http.MethodGet, "/dates/{date}", DatePage},
+	{http.MethodGet, "/members/{$}", MembersPage},
+	{http.MethodGet, "/members/{slug}", MemberPage},
+	{http.MethodGet, "/compare", ComparePage},
+	{http.MethodGet, "/stats", StatsPage},
+	{http.MethodGet, "/calendar", CalendarPage},
+	{http.MethodGet, "/upcoming", UpcomingPage},
+	{http.MethodGet, "/nearby", NearbyPage},
+	{http.MethodGet, "/metrics", Metrics},
+	{http.MethodGet, "/healthz", Healthz},
+	{http.MethodGet, "/readyz", Readyz},
+}
+
+// Routes returns the handler serving the routes. A request for a known path with
+// another method gets a 405 page with an Allow header, any other path a 404 page.
+// Under /api/ the errors are JSON. Every route is counted in the metrics.
+func Routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	allowed := make(map[string][]string)
+	var patterns []string
+	for _, rt := range routes {
+		mux.HandleFunc(rt.method+" "+rt.pattern, instrument(rt.method+" "+rt.pattern, rt.handler))
+		if _, ok := allowed[rt.pattern]; !ok {
+			patterns = append(patterns, rt.pattern)
+		}
+		allowed[rt.pattern] = append(allowed[rt.pattern], rt.method)
+		if rt.method == http.MethodGet {
+			allowed[rt.pattern] = append(allowed[rt.pattern], http.MethodHead)
+		}
+	}
+	// Patterns without a method only get the requests the routes above did not take
+	for _, pattern := range patterns {
+		mux.HandleFunc(pattern, instrument(pattern, methodNotAllowed(allowed[pattern])))
+	}
+	mux.HandleFunc("/", instrument("unmatched", func(w http.ResponseWriter, r *http.Request) {
+		routeError(w, r, http.StatusNotFound)
+	}))
+	return mux
+}
+
+// routeError renders the error page, or the JSON error of API paths.
+func routeError(w http.ResponseWriter, r *http.Request, code int) {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		apiError(w, code)
+		return
+	}
+	ErrorPage(w, code)
+}
+
+// methodNotAllowed renders a 405 page listing the allowed methods.
+func methodNotAllowed(methods []string) http.HandlerFunc {
+	allow := strings.Join(methods, ", ")
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		routeError(w, r, http.StatusMethodNotAllowed)
+	}
+}
+
+// RedirectArtistQuery permanently redirects the old /artists/?id=3 URLs to the artist page.
+func RedirectArtistQuery(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		loggerFrom(r.Context()).Warn("invalid artist ID", "err", err)
+		ErrorPage(w, http.StatusBadRequest)
+		return
+	}
+	d := currentData()
+	artist, ok := d.artistByID(id)
+	if !ok {
+		loggerFrom(r.Context()).Warn("unknown artist", "id", id)
+		ErrorPage(w, http.StatusBadRequest)
+		return
+	}
+	http.Redirect(w, r, d.artistPath(artist.ID), http.StatusMovedPermanently)
+}
+
+// RedirectSearch permanently redirects the old /search/?q= URLs to /search.
+func RedirectSearch(w http.ResponseWriter, r *http.Request) {
+	target := url.URL{Path: "/search", RawQuery: r.URL.RawQuery}
+	http.Redirect(w, r, target.String(), http.StatusMovedPermanently)
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	setupConcertData()
+	useTemplates(t, "stats.html")
+
+	tests := []struct {
+		name             string
+		method           string
+		path             string
+		expectedCode     int
+		expectedAllow    string
+		expectedLocation string
+	}{
+		{"Page", http.MethodGet, "/stats", http.StatusOK, "", ""},
+		{"HEAD of a page", http.MethodHead, "/stats", http.StatusOK, "", ""},
+		{"Wrong method", http.MethodPost, "/stats", http.StatusMethodNotAllowed, "GET, HEAD", ""},
+		{"Wrong method on a path pattern", http.MethodDelete, "/members/brian-may", http.StatusMethodNotAllowed, "GET, HEAD", ""},
+		{"Wrong method on the home page", http.MethodPut, "/", http.StatusMethodNotAllowed, "GET, HEAD", ""},
+		{"Unknown path", http.MethodGet, "/nowhere", http.StatusNotFound, "", ""},
+		{"Unknown path with another method", http.MethodPost, "/nowhere", http.StatusNotFound, "", ""},
+		{"Trailing slash", http.MethodGet, "/stats/", http.StatusNotFound, "", ""},
+		{"Old search URL", http.MethodGet, "/search/?q=pink+floyd", http.StatusMovedPermanently, "", "/search?q=pink+floyd"},
+		{"Old artist URL", http.MethodGet, "/artists/?id=2", http.StatusMovedPermanently, "", "/artists/pink-floyd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+			Routes().ServeHTTP(w, r)
+			if w.Code != tt.expectedCode {
+				t.Fatalf("%s %s: status code = %d, want %d", tt.method, tt.path, w.Code, tt.expectedCode)
+			}
+			if allow := w.Header().Get("Allow"); allow != tt.expectedAllow {
+				t.Errorf("%s %s: Allow = %q, want %q", tt.method, tt.path, allow, tt.expectedAllow)
+			}
+			if location := w.Header().Get("Location"); location != tt.expectedLocation {
+				t.Errorf("%s %s: Location = %q, want %q", tt.method, tt.path, location, tt.expectedLocation)
+			}
+		})
+	}
+}
+
+func TestRoutesAPIErrors(t *testing.T) {
+	setupConcertData()
+
+	tests := []struct {
+		method       string
+		path         string
+		expectedCode int
+	}{
+		{http.MethodPost, "/api/artists", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/api/nowhere", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		w := httptest.NewRecorder()
+		Routes().ServeHTTP(w, r)
+
+		var body apiErrorBody
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != tt.expectedCode {
+			t.Errorf("%s %s = %d %s, want a JSON %d error", tt.method, tt.path, w.Code, w.Body.String(), tt.expectedCode)
+		}
+	}
+}
+
+func TestArtistSlug(t *testing.T) {
+	list, rels := concertData()
+	list = append(list,
+		Artist{ID: 3, Name: "Motörhead"},
+		Artist{ID: 4, Name: "Pink  FLOYD"},
+		Artist{ID: 5, Name: "!!!"},
+	)
+	d := useData(list, rels)
+
+	tests := []struct {
+		id       int
+		expected string
+	}{
+		{1, "queen"},
+		{3, "motorhead"},
+		{2, "pink-floyd-2"},
+		{4, "pink-floyd-4"},
+		{5, "5"},
+	}
+	for _, tt := range tests {
+		artist, _ := d.artistByID(tt.id)
+		if got := d.slug(artist); got != tt.expected {
+			t.Errorf("slug() of %q = %q, want %q", artist.Name, got, tt.expected)
+		}
+		found, err := d.artistFromParam(tt.expected)
+		if err != nil || found.ID != tt.id {
+			t.Errorf("artistFromParam(%q) = %d, %v, want artist %d", tt.expected, found.ID, err, tt.id)
+		}
+	}
+
+	for param, id := range map[string]int{"Queen": 1, "Motörhead": 3, "Pink-Floyd-4": 4} {
+		if found, err := d.artistFromParam(param); err != nil || found.ID != id {
+			t.Errorf("artistFromParam(%q) = %d, %v, want artist %d", param, found.ID, err, id)
+		}
+	}
+	if _, err := d.artistFromParam("pink-floyd"); err == nil {
+		t.Error("artistFromParam() resolved a slug shared by two artists")
+	}
+	if got := (TemplateData{dataset: d}).ArtistPath(3); got != "/artists/motorhead" {
+		t.Errorf("ArtistPath() = %q, want /artists/motorhead", got)
+	}
+}
+
+func TestArtistPath_RenderedDataset(t *testing.T) {
+	useTemplates(t, "index.html")
+	list, rels := concertData()
+	d := useData(list, rels)
+
+	// A refresh during the render must not change the links of the page
+	renamed := append([]Artist(nil), list...)
+	renamed[0].Name = "Queen II"
+	useData(renamed, rels)
+	w := httptest.NewRecorder()
+	renderTemplate(w, "index.html", TemplateData{Data: d.artists, dataset: d})
+	if body := w.Body.String(); !strings.Contains(body, `href="/artists/queen"`) || strings.Contains(body, "/artists/queen-ii") {
+		t.Errorf("index page = %s, want the links of the dataset it was built from", body)
+	}
+}
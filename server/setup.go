@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"groupie-tracker/config"
+	"groupie-tracker/web"
+)
+
+// staticFS holds the files served under /static/
+var staticFS = web.Static("")
+
+// devMode reads the templates and static files from disk and reloads the templates when they change.
+var devMode bool
+
+// staticCacheTTL is how long browsers may cache static files, zero to leave it to them
+var staticCacheTTL time.Duration
+
+// Configure applies the settings to the package. It must be called before Load.
+func Configure(cfg config.Config) {
+	upstream := strings.TrimSuffix(cfg.UpstreamURL, "/")
+	artistsURL = upstream + "/artists"
+	relationsURL = upstream + "/relation"
+	// Development mode reads the files from disk so that edits show without a rebuild
+	devMode = cfg.Dev
+	templatesFS, staticFS = web.Templates(""), web.Static("")
+	if cfg.Dev {
+		templatesFS, staticFS = web.Templates(cfg.TemplatesDir), web.Static(cfg.StaticDir)
+	}
+	gazetteerOverrides = filepath.Join(cfg.DataDir, "gazetteer_overrides.csv")
+	snapshotPath = filepath.Join(cfg.DataDir, "snapshot.json")
+	upstreamClient = &http.Client{Timeout: time.Duration(cfg.UpstreamTimeout)}
+	cacheTTL = time.Duration(cfg.CacheTTL)
+	staticCacheTTL = time.Duration(cfg.StaticCacheTTL)
+	Similarity = cfg.Similarity
+	// Without refreshes the data only ages, which is no reason to stop serving it
+	readyMaxAge = 0
+	if cfg.RefreshInterval > 0 {
+		readyMaxAge = time.Duration(cfg.ReadyMaxAge)
+	}
+	if day, ok := cfg.FixedNow(); ok {
+		SetNow(day)
+	}
+}
+
+// Load reads the templates and the gazetteer and fetches the artists and their concerts.
+// When the upstream API cannot be reached it serves the last snapshot instead, or
+// nothing until RunRefresher gets the data; only missing templates or gazetteer fail,
+// and the templates not even in development mode where they can still be fixed.
+func Load() error {
+	pages, err := loadTemplates()
+	if err != nil && !devMode {
+		return err
+	}
+	if err != nil {
+		slog.Error("could not load the templates", "err", err)
+	}
+	setTemplates(pages, err)
+	if err := loadPlaces(); err != nil {
+		return fmt.Errorf("could not load gazetteer: %w", err)
+	}
+	if err := LoadData(); err != nil {
+		slog.Error("could not load the data", "err", err)
+		savedAt, err := loadSnapshot()
+		if err != nil {
+			slog.Warn("no snapshot to fall back to, serving the maintenance page until the data is loaded", "err", err)
+			return nil
+		}
+		slog.Warn("serving the snapshot until the upstream API is back", "saved", savedAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// LoadData fetches the artists and their concerts and recomputes the data derived from them.
+// The current data is only replaced once everything was fetched.
+func LoadData() error {
+	return loadData(context.Background())
+}
+
+// loadData works like LoadData but gives up when the context is done.
+func loadData(ctx context.Context) (err error) {
+	source := strings.TrimSuffix(artistsURL, "/artists")
+	defer func() {
+		if err != nil {
+			refreshErrors.Inc()
+		}
+		recordRefresh(source, err)
+	}()
+
+	list, err := FetchArtists(ctx)
+	if err != nil {
+		return fmt.Errorf("could not fetch artists: %w", err)
+	}
+	// An empty list is an upstream problem rather than every artist leaving
+	if len(list) == 0 {
+		return fmt.Errorf("could not fetch artists: %s returned none", artistsURL)
+	}
+	rels, err := fetchRelations(ctx)
+	if err != nil {
+		return fmt.Errorf("could not fetch relations: %w", err)
+	}
+	swapData(list, rels, time.Now())
+	upstreamLoaded.Store(true)
+	if err := saveSnapshot(source, list, rels); err != nil {
+		slog.Error("could not save snapshot", "err", err)
+	}
+	return nil
+}
+
+// swapData replaces the artists and their concerts with a dataset computed from them,
+// loaded at the given time. Requests in progress keep the dataset they started with.
+func swapData(list []Artist, rels map[int]Relation, loadedAt time.Time) {
+	d := newDataset(list, rels)
+	if missing := d.unresolvedLocations(); len(missing) > 0 {
+		slog.Warn("concert locations are not in the gazetteer, run \"groupie-tracker gazetteer\" for a report", "count", len(missing))
+	}
+	current.Store(d)
+	artistCount.Set(float64(len(list)))
+	lastRefresh.Set(float64(loadedAt.Unix()))
+	dataLoaded.Store(true)
+}
+
+// RunRefresher keeps the data up to date until the context is done, which also
+// abandons a load in progress. Successful loads are repeated every interval, or
+// stop once the data is loaded when interval is zero. A failed load is retried
+// after retryMin, then twice as late each time up to retryMax, keeping the data
+// already loaded; so is a snapshot until the upstream API answers.
+func RunRefresher(ctx context.Context, interval time.Duration) {
+	defer nextAttempt.Store(0)
+	backoff := retryMin
+	delay := interval
+	if !upstreamLoaded.Load() {
+		delay = backoff
+	}
+	for delay > 0 {
+		nextAttempt.Store(time.Now().Add(delay).UnixNano())
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := loadData(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Error("refresh failed", "retry", backoff, "err", err)
+			delay = backoff
+			backoff = min(2*backoff, retryMax)
+			continue
+		}
+		slog.Info("data refreshed")
+		backoff = retryMin
+		delay = interval
+	}
+}
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"groupie-tracker/config"
+)
+
+// fakeUpstream serves a small copy of the groupie trackers API and counts the requests it gets.
+type fakeUpstream struct {
+	*httptest.Server
+	requests atomic.Int32
+}
+
+// newFakeUpstream starts an upstream API with one artist, closed when the test ends.
+func newFakeUpstream(t *testing.T) *fakeUpstream {
+	t.Helper()
+	f := &fakeUpstream{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.requests.Add(1)
+		base := "http://" + r.Host
+		var body interface{}
+		switch r.URL.Path {
+		case "/artists":
+			body = []Artist{{
+				ID:           1,
+				Name:         "Test Artist",
+				Members:      []string{"Test Member"},
+				CreationDate: 1970,
+				FirstAlbum:   "14-12-1973",
+				Locations:    base + "/locations/1",
+				ConcertDates: base + "/dates/1",
+				Relations:    base + "/relation/1",
+			}}
+		case "/locations/1":
+			body = Loc{Locations: []string{"london-uk"}}
+		case "/dates/1":
+			body = Date{Dates: []string{"*01-01-2021"}}
+		case "/relation/1":
+			body = Relation{ID: 1, DatesLocation: map[string][]string{"london-uk": {"01-01-2021"}}}
+		case "/relation":
+			body = map[string][]Relation{"index": {{ID: 1, DatesLocation: map[string][]string{"london-uk": {"01-01-2021"}}}}}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// useUpstream points the package at the fake upstream and restores the previous settings after the test.
+func useUpstream(t *testing.T, f *fakeUpstream) {
+	t.Helper()
+	oldArtists, oldRelations, oldTTL := artistsURL, relationsURL, cacheTTL
+	t.Cleanup(func() {
+		artistsURL, relationsURL, cacheTTL = oldArtists, oldRelations, oldTTL
+		cacheMu.Lock()
+		responseCache = make(map[string]cachedResponse)
+		cacheMu.Unlock()
+	})
+	artistsURL = f.URL + "/artists"
+	relationsURL = f.URL + "/relation"
+}
+
+func TestConfigure(t *testing.T) {
+	old := struct {
+		artists, relations, overrides, snapshot string
+		templates, static                       fs.FS
+		ttl, staticTTL                          time.Duration
+		weights                                 SimilarityWeights
+	}{artistsURL, relationsURL, gazetteerOverrides, snapshotPath, templatesFS, staticFS, cacheTTL, staticCacheTTL, Similarity}
+	t.Cleanup(func() {
+		artistsURL, relationsURL, gazetteerOverrides, snapshotPath = old.artists, old.relations, old.overrides, old.snapshot
+		templatesFS, staticFS = old.templates, old.static
+		cacheTTL, staticCacheTTL, Similarity = old.ttl, old.staticTTL, old.weights
+		now = time.Now
+	})
+
+	cfg := config.Default()
+	cfg.UpstreamURL = "http://upstream.test/api/"
+	cfg.DataDir = "/srv/groupie"
+	cfg.CacheTTL = config.Duration(time.Minute)
+	cfg.Similarity.SharedMember = 9
+	cfg.Now = "2020-01-01"
+	Configure(cfg)
+
+	if artistsURL != "http://upstream.test/api/artists" || relationsURL != "http://upstream.test/api/relation" {
+		t.Errorf("upstream URLs = %s, %s", artistsURL, relationsURL)
+	}
+	if gazetteerOverrides != "/srv/groupie/gazetteer_overrides.csv" || snapshotPath != "/srv/groupie/snapshot.json" {
+		t.Errorf("gazetteer overrides = %s, snapshot = %s", gazetteerOverrides, snapshotPath)
+	}
+	if cacheTTL != time.Minute || Similarity.SharedMember != 9 {
+		t.Errorf("cache TTL = %s, shared member weight = %v", cacheTTL, Similarity.SharedMember)
+	}
+	if got := today().Format(dayLayout); got != "2020-01-01" {
+		t.Errorf("today() = %s, want 2020-01-01", got)
+	}
+
+	// Development mode reads the files from the configured directories
+	cfg.Dev = true
+	cfg.TemplatesDir = t.TempDir()
+	cfg.StaticDir = "../web/static"
+	Configure(cfg)
+	if _, err := fs.Stat(templatesFS, "layout.html"); err == nil {
+		t.Error("templates are not read from the templates dir in development mode")
+	}
+	if _, err := fs.Stat(staticFS, "style.css"); err != nil {
+		t.Errorf("static files are not read from the static dir in development mode: %v", err)
+	}
+}
+
+func TestLoadData(t *testing.T) {
+	setupConcertData()
+	upstream := newFakeUpstream(t)
+	useUpstream(t, upstream)
+
+	if err := LoadData(); err != nil {
+		t.Fatal(err)
+	}
+	d := currentData()
+	if len(d.artists) != 1 || d.artists[0].Name != "Test Artist" {
+		t.Fatalf("artists = %+v, want the upstream artist", d.artists)
+	}
+	if _, ok := d.relations[1].DatesLocation["london-uk"]; !ok {
+		t.Errorf("relations = %+v, want the upstream relation", d.relations)
+	}
+	if d.stats.Artists != 1 || d.stats.Concerts != 1 {
+		t.Errorf("statistics = %d artists and %d concerts, want 1 and 1", d.stats.Artists, d.stats.Concerts)
+	}
+
+	// A failed reload keeps the data already loaded
+	artistsURL = upstream.URL + "/missing"
+	if err := LoadData(); err == nil {
+		t.Fatal("LoadData() from a missing URL succeeded")
+	}
+	if currentData() != d {
+		t.Errorf("a failed reload replaced the artists with %+v", currentData().artists)
+	}
+
+	// So does an upstream answering without any artist
+	empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "[]")
+	}))
+	defer empty.Close()
+	artistsURL = empty.URL + "/artists"
+	if err := LoadData(); err == nil {
+		t.Fatal("LoadData() of an empty artist list succeeded")
+	}
+	if currentData() != d {
+		t.Errorf("an empty artist list replaced the artists with %+v", currentData().artists)
+	}
+}
+
+func TestSwapData_SlowRequest(t *testing.T) {
+	useTemplates(t, "details.html")
+	hung := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(hung)
+		<-r.Context().Done()
+	}))
+	defer upstream.Close()
+	list, rels := concertData()
+	list[0].Locations = upstream.URL + "/locations/1"
+	useData(list, rels)
+
+	// A page waiting for the upstream API
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		w := httptest.NewRecorder()
+		Routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/artists/queen", nil).WithContext(ctx))
+		done <- w
+	}()
+	<-hung
+
+	// does not hold up a refresh
+	swapData([]Artist{{ID: 1, Name: "Test Artist"}}, nil, time.Now())
+	if d := currentData(); len(d.artists) != 1 || d.artists[0].Name != "Test Artist" {
+		t.Errorf("artists after the refresh = %+v, want the new one", d.artists)
+	}
+
+	// and stops waiting when the client leaves, finishing with the data it started with
+	cancel()
+	select {
+	case w := <-done:
+		if !strings.Contains(w.Body.String(), "Los Angeles") {
+			t.Errorf("page during a refresh = %d %s, want the concerts it started with", w.Code, w.Body.String())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the page kept waiting for the upstream API after the request was canceled")
+	}
+}
+
+func TestRunRefresher(t *testing.T) {
+	// An upstream that never answers, so that the refresh is in progress when the context ends
+	hung := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(hung)
+		<-r.Context().Done()
+	}))
+	defer upstream.Close()
+	oldURL, oldRetry := artistsURL, retryMin
+	defer func() { artistsURL, retryMin = oldURL, oldRetry }()
+	artistsURL = upstream.URL + "/artists"
+	retryMin = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		RunRefresher(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	<-hung
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunRefresher() did not return after the context was canceled")
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// snapshotPath is the file the data is saved to after each load, to be served
+// when the upstream API is down at startup. Empty disables snapshots.
+var snapshotPath string
+
+// snapshot is the data saved on disk.
+type snapshot struct {
+	SavedAt   time.Time  `json:"savedAt"`
+	Source    string     `json:"source"`
+	Artists   []Artist   `json:"artists"`
+	Relations []Relation `json:"relations"`
+}
+
+// saveSnapshot writes the data to snapshotPath. The file is replaced in one
+// rename so that a crash never leaves half a snapshot.
+func saveSnapshot(source string, list []Artist, rels map[int]Relation) error {
+	if snapshotPath == "" {
+		return nil
+	}
+	snap := snapshot{SavedAt: time.Now().UTC(), Source: source, Artists: list}
+	for _, rel := range rels {
+		snap.Relations = append(snap.Relations, rel)
+	}
+	sort.Slice(snap.Relations, func(i, j int) bool {
+		return snap.Relations[i].ID < snap.Relations[j].ID
+	})
+
+	if err := os.MkdirAll(filepath.Dir(snapshotPath), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(snapshotPath), ".snapshot-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := json.NewEncoder(tmp).Encode(snap); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), snapshotPath)
+}
+
+// readSnapshot reads the snapshot saved at snapshotPath.
+func readSnapshot() (snapshot, error) {
+	var snap snapshot
+	if snapshotPath == "" {
+		return snap, fmt.Errorf("snapshots are disabled")
+	}
+	data, err := os.ReadFile(snapshotPath)
+	if err != nil {
+		return snap, err
+	}
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return snap, fmt.Errorf("invalid snapshot %s: %w", snapshotPath, err)
+	}
+	if len(snap.Artists) == 0 {
+		return snap, fmt.Errorf("snapshot %s has no artists", snapshotPath)
+	}
+	return snap, nil
+}
+
+// loadSnapshot serves the data saved in the snapshot, reporting it as loaded
+// when the snapshot was saved.
+func loadSnapshot() (time.Time, error) {
+	snap, err := readSnapshot()
+	if err != nil {
+		return time.Time{}, err
+	}
+	rels := make(map[int]Relation, len(snap.Relations))
+	for _, rel := range snap.Relations {
+		rels[rel.ID] = rel
+	}
+	swapData(snap.Artists, rels, snap.SavedAt)
+	recordSnapshot(snapshotPath, snap.SavedAt)
+	return snap.SavedAt, nil
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useSnapshot saves the snapshots in a temporary directory and restores the previous path after the test.
+func useSnapshot(t *testing.T) string {
+	t.Helper()
+	old := snapshotPath
+	t.Cleanup(func() { snapshotPath = old })
+	snapshotPath = filepath.Join(t.TempDir(), "data", "snapshot.json")
+	return snapshotPath
+}
+
+func TestSnapshot(t *testing.T) {
+	resetStatus(t)
+	upstream := newFakeUpstream(t)
+	useUpstream(t, upstream)
+	path := useSnapshot(t)
+
+	if err := LoadData(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("LoadData() did not save a snapshot: %v", err)
+	}
+
+	setupConcertData()
+	savedAt, err := loadSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := currentData()
+	if len(d.artists) != 1 || d.artists[0].Name != "Test Artist" {
+		t.Errorf("artists = %+v, want the artist of the snapshot", d.artists)
+	}
+	if _, ok := d.relations[1].DatesLocation["london-uk"]; !ok {
+		t.Errorf("relations = %+v, want the relation of the snapshot", d.relations)
+	}
+	if d.stats.Artists != 1 {
+		t.Errorf("statistics count %d artists, want 1", d.stats.Artists)
+	}
+
+	r := checkReadiness()
+	if r.Source != "snapshot "+path || r.LastRefresh != savedAt.Format("2006-01-02T15:04:05Z07:00") {
+		t.Errorf("readiness = %+v, want the snapshot saved at %s", r, savedAt)
+	}
+}
+
+func TestLoadSnapshot_LastRefresh(t *testing.T) {
+	resetStatus(t)
+	useNoData(t)
+	path := useSnapshot(t)
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	content := `{"savedAt":"2020-01-02T03:04:05Z","source":"test","artists":[{"id":1,"name":"Old Artist"}]}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The data is as old as the snapshot, not as the restart
+	savedAt, err := loadSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := lastRefresh.Value(); got != float64(savedAt.Unix()) {
+		t.Errorf("last refresh = %v, want the time the snapshot was saved %d", got, savedAt.Unix())
+	}
+}
+
+func TestReadSnapshot_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"Missing file", "", "no such file"},
+		{"Invalid JSON", "{", "invalid snapshot"},
+		{"No artists", `{"artists":[]}`, "has no artists"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := useSnapshot(t)
+			if tt.content != "" {
+				os.MkdirAll(filepath.Dir(path), 0o755)
+				if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := readSnapshot(); err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("readSnapshot() error = %v, want %q", err, tt.expected)
+			}
+		})
+	}
+
+	useSnapshot(t)
+	snapshotPath = ""
+	if err := saveSnapshot("test", []Artist{{ID: 1}}, nil); err != nil {
+		t.Errorf("saveSnapshot() without a path = %v, want nothing saved", err)
+	}
+	if _, err := readSnapshot(); err == nil {
+		t.Error("readSnapshot() without a path succeeded")
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"groupie-tracker/gazetteer"
+)
+
+// statsLimit is the number of entries shown in each ranking of the statistics page.
+const statsLimit = 10
+
+// StatsChart is a titled SVG chart of the statistics page.
+type StatsChart struct {
+	Title string
+	SVG   string
+}
+
+// DatasetStats holds the figures and charts of the statistics page.
+type DatasetStats struct {
+	Artists   int
+	Concerts  int
+	Cities    int
+	Countries int
+	Charts    []StatsChart
+}
+
+// rankValues sorts the values by decreasing count, then label, and keeps the first limit ones.
+func rankValues(values []ChartValue, limit int) []ChartValue {
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Count != values[j].Count {
+			return values[i].Count > values[j].Count
+		}
+		return values[i].Label < values[j].Label
+	})
+	if len(values) > limit {
+		values = values[:limit]
+	}
+	return values
+}
+
+// countValues counts the values of a key, keeping the first label and path given for it.
+type countValues struct {
+	keys   []string
+	values map[string]*ChartValue
+}
+
+func (c *countValues) add(key, label, path string, n int) {
+	if c.values == nil {
+		c.values = make(map[string]*ChartValue)
+	}
+	v, ok := c.values[key]
+	if !ok {
+		v = &ChartValue{Label: label, Path: path}
+		c.values[key] = v
+		c.keys = append(c.keys, key)
+	}
+	v.Count += n
+}
+
+// list returns the counted values in key order.
+func (c *countValues) list() []ChartValue {
+	sort.Strings(c.keys)
+	values := make([]ChartValue, len(c.keys))
+	for i, key := range c.keys {
+		values[i] = *c.values[key]
+	}
+	return values
+}
+
+// computeStats aggregates the artists and their concerts into the statistics page. It
+// is computed when the data is loaded so pages don't recount every concert.
+func (d *dataset) computeStats(concerts []Concert) DatasetStats {
+	var cities, countries, years, byArtist, decades, members countValues
+	for _, c := range concerts {
+		slug := gazetteer.Normalize(c.Location)
+		cities.add(slug, locationName(slug), "/locations/"+slug, 1)
+		country := locationCountry(slug)
+		countries.add(country, country, "", 1)
+		year := c.Date.Format(yearLayout)
+		years.add(year, year, "/dates/"+year, 1)
+		byArtist.add(fmt.Sprintf("%06d", c.ArtistID), c.Artist, d.artistPath(c.ArtistID), 1)
+	}
+	for _, artist := range d.artists {
+		decade := artist.CreationDate / 10 * 10
+		decades.add(strconv.Itoa(decade), fmt.Sprintf("%ds", decade), "", 1)
+		n := len(artist.Members)
+		// Zero-padded keys keep the counts in numeric order
+		members.add(fmt.Sprintf("%03d", n), strconv.Itoa(n), "", 1)
+	}
+
+	cityList := cities.list()
+	countryList := countries.list()
+	return DatasetStats{
+		Artists:   len(d.artists),
+		Concerts:  len(concerts),
+		Cities:    len(cityList),
+		Countries: len(countryList),
+		Charts: []StatsChart{
+			{"Most visited cities", renderBarChart("Most visited cities", rankValues(cityList, statsLimit))},
+			{"Most visited countries", renderBarChart("Most visited countries", rankValues(countryList, statsLimit))},
+			{"Busiest years", renderBarChart("Busiest years", rankValues(years.list(), statsLimit))},
+			{"Artists with the most concerts", renderBarChart("Artists with the most concerts", rankValues(byArtist.list(), statsLimit))},
+			{"Artists formed per decade", renderColumnChart("Artists formed per decade", decades.list())},
+			{"Artists by number of members", renderColumnChart("Artists by number of members", members.list())},
+		},
+	}
+}
+
+// StatsPage shows statistics over every artist and concert.
+func StatsPage(w http.ResponseWriter, r *http.Request) {
+	data := TemplateData{
+		Title: "Statistics",
+		Stats: currentData().stats,
+	}
+	renderTemplate(w, "stats.html", data)
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"groupie-tracker/gazetteer"
+)
+
+func TestRankValues(t *testing.T) {
+	values := []ChartValue{{Label: "b", Count: 1}, {Label: "c", Count: 3}, {Label: "a", Count: 1}, {Label: "d", Count: 2}}
+	got := rankValues(values, 3)
+	expected := []ChartValue{{Label: "c", Count: 3}, {Label: "d", Count: 2}, {Label: "a", Count: 1}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("rankValues() = %v, want %v", got, expected)
+	}
+}
+
+func TestComputeStats(t *testing.T) {
+	places = gazetteer.Default()
+	d := setupConcertData()
+	concerts, err := d.allConcerts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats := d.computeStats(concerts)
+	if stats.Artists != 2 || stats.Concerts != 4 || stats.Cities != 3 || stats.Countries != 3 {
+		t.Errorf("computeStats() = %d artists, %d concerts, %d cities, %d countries, want 2, 4, 3, 3",
+			stats.Artists, stats.Concerts, stats.Cities, stats.Countries)
+	}
+
+	expected := map[string][]string{
+		"Most visited cities":            {`<a href="/locations/los_angeles-usa"><text`, "Los Angeles, United States: 2"},
+		"Most visited countries":         {"United States: 2", "France: 1"},
+		"Busiest years":                  {`href="/dates/2019"`, "2019: 2"},
+		"Artists with the most concerts": {`href="/artists/queen"`, "Queen: 3", "Pink Floyd: 1"},
+		"Artists formed per decade":      {"1960s: 1", "1970s: 1"},
+		"Artists by number of members":   {"<title>1: 1</title>", "<title>2: 1</title>"},
+	}
+	if len(stats.Charts) != len(expected) {
+		t.Fatalf("computeStats() returned %d charts, want %d", len(stats.Charts), len(expected))
+	}
+	for _, chart := range stats.Charts {
+		texts, ok := expected[chart.Title]
+		if !ok {
+			t.Errorf("unexpected chart %q", chart.Title)
+			continue
+		}
+		for _, text := range texts {
+			if !strings.Contains(chart.SVG, text) {
+				t.Errorf("chart %q doesn't contain %q", chart.Title, text)
+			}
+		}
+	}
+	// Decades are in chronological order
+	decades := stats.Charts[4].SVG
+	if strings.Index(decades, "1960s") > strings.Index(decades, "1970s") {
+		t.Errorf("decades are not in chronological order")
+	}
+}
+
+func TestStatsPage(t *testing.T) {
+	useTemplates(t, "stats.html")
+	current.Store(&dataset{stats: DatasetStats{Artists: 52, Charts: []StatsChart{{"Busiest years", renderBarChart("Busiest years", nil)}}}})
+	defer current.Store(nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/stats", nil)
+	w := httptest.NewRecorder()
+	StatsPage(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("StatsPage() status code = %d, want %d", w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	for _, text := range []string{"52", "Busiest years", `<svg xmlns="http://www.w3.org/2000/svg"`} {
+		if !strings.Contains(body, text) {
+			t.Errorf("StatsPage() response doesn't contain %q", text)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kinds of timeline events.
+const (
+	eventFormed     = "formed"
+	eventFirstAlbum = "album"
+	eventConcert    = "concert"
+)
+
+// TimelineEvent is a dated milestone of an artist's career.
+type TimelineEvent struct {
+	Date     time.Time
+	Kind     string
+	Label    string
+	Location string // concert location slug, empty for other events
+}
+
+// Day returns the event date, or only its year for the creation which has no exact date.
+func (e TimelineEvent) Day() string {
+	if e.Kind == eventFormed {
+		return e.Date.Format(yearLayout)
+	}
+	return e.Date.Format(dateLayout)
+}
+
+// TimelineYear holds the events of one year of the timeline.
+type TimelineYear struct {
+	Year     int
+	Concerts int
+	Events   []TimelineEvent
+	Open     bool // shown expanded on page load
+}
+
+// Timeline is an artist's career in chronological order.
+type Timeline struct {
+	Years []TimelineYear
+	SVG   string
+}
+
+// Size of the timeline strip in SVG user units.
+const (
+	timelineWidth  = 720
+	timelineHeight = 70
+	timelineMargin = 20
+)
+
+// artistTimeline lays out the creation, first album and concerts of an artist by year.
+func artistTimeline(artist Artist, concerts []Concert) Timeline {
+	var events []TimelineEvent
+	if artist.CreationDate > 0 {
+		events = append(events, TimelineEvent{
+			Date:  time.Date(artist.CreationDate, time.January, 1, 0, 0, 0, 0, time.UTC),
+			Kind:  eventFormed,
+			Label: artist.Name + " formed",
+		})
+	}
+	if album, err := parseDate(artist.FirstAlbum); err == nil {
+		events = append(events, TimelineEvent{Date: album, Kind: eventFirstAlbum, Label: "First album released"})
+	}
+	for _, c := range concerts {
+		events = append(events, TimelineEvent{Date: c.Date, Kind: eventConcert, Label: c.LocationName(), Location: c.Location})
+	}
+	// Concerts are already sorted, the creation and album only need to be moved into place
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Date.Before(events[j].Date)
+	})
+
+	var timeline Timeline
+	for _, e := range events {
+		if n := len(timeline.Years); n == 0 || timeline.Years[n-1].Year != e.Date.Year() {
+			timeline.Years = append(timeline.Years, TimelineYear{Year: e.Date.Year()})
+		}
+		year := &timeline.Years[len(timeline.Years)-1]
+		year.Events = append(year.Events, e)
+		if e.Kind == eventConcert {
+			year.Concerts++
+		}
+	}
+	if n := len(timeline.Years); n > 0 {
+		timeline.Years[n-1].Open = true
+	}
+	timeline.SVG = renderTimeline("Timeline of "+artist.Name, events)
+	return timeline
+}
+
+// yearFraction returns the position of t in years, e.g. 2019.5 in early July 2019.
+func yearFraction(t time.Time) float64 {
+	start := time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+	return float64(t.Year()) + t.Sub(start).Hours()/end.Sub(start).Hours()
+}
+
+// renderTimeline draws the events on a horizontal strip with a tick per year,
+// or per five or ten years for long careers.
+func renderTimeline(title string, events []TimelineEvent) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" class="timeline-strip" role="img" aria-label="%s">`+"\n",
+		timelineWidth, timelineHeight, html.EscapeString(title))
+	if len(events) == 0 {
+		b.WriteString("</svg>")
+		return b.String()
+	}
+
+	first, last := events[0].Date.Year(), events[len(events)-1].Date.Year()+1
+	step := 1
+	if span := last - first; span > 40 {
+		step = 10
+	} else if span > 12 {
+		step = 5
+	}
+	// Start the axis on a tick
+	first -= first % step
+	x := func(year float64) float64 {
+		return timelineMargin + (year-float64(first))/float64(last-first)*(timelineWidth-2*timelineMargin)
+	}
+
+	const axis = 40
+	fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#5c5652" stroke-width="2"/>`+"\n",
+		timelineMargin, axis, timelineWidth-timelineMargin, axis)
+	b.WriteString(`<g font-size="11" fill="#f2f0ef" text-anchor="middle">` + "\n")
+	for year := first; year <= last; year += step {
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#5c5652"/>`+"\n", x(float64(year)), axis-4, x(float64(year)), axis+4)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d">%d</text>`+"\n", x(float64(year)), axis+20, year)
+	}
+	b.WriteString("</g>\n")
+
+	for _, e := range events {
+		pos := x(yearFraction(e.Date))
+		tooltip := html.EscapeString(e.Day() + ": " + e.Label)
+		switch e.Kind {
+		case eventFormed:
+			fmt.Fprintf(&b, `<rect x="%.1f" y="%d" width="10" height="10" fill="#F2EF72"><title>%s</title></rect>`+"\n", pos-5, axis-20, tooltip)
+		case eventFirstAlbum:
+			fmt.Fprintf(&b, `<polygon points="%.1f,%d %.1f,%d %.1f,%d %.1f,%d" fill="#6fc3df"><title>%s</title></polygon>`+"\n",
+				pos, axis-22, pos+6, axis-15, pos, axis-8, pos-6, axis-15, tooltip)
+		default:
+			fmt.Fprintf(&b, `<circle cx="%.1f" cy="%d" r="3" fill="#ed2100" fill-opacity="0.8"><title>%s</title></circle>`+"\n", pos, axis, tooltip)
+		}
+	}
+	b.WriteString("</svg>")
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"testing"
+
+	"groupie-tracker/gazetteer"
+)
+
+func TestArtistTimeline(t *testing.T) {
+	d := setupConcertData()
+	places = gazetteer.Default()
+	queen := d.artists[0]
+	queen.FirstAlbum = "14-12-2019"
+
+	timeline := artistTimeline(queen, concertsOf(queen, d.relations[1]))
+
+	var years []int
+	for _, year := range timeline.Years {
+		years = append(years, year.Year)
+	}
+	if len(years) != 3 || years[0] != 1970 || years[1] != 2019 || years[2] != 2020 {
+		t.Fatalf("timeline years = %v, want [1970 2019 2020]", years)
+	}
+
+	// The album comes after the concerts of its year
+	y2019 := timeline.Years[1]
+	kinds := []string{eventConcert, eventConcert, eventFirstAlbum}
+	if y2019.Concerts != 2 || len(y2019.Events) != len(kinds) {
+		t.Fatalf("2019 events = %+v, want 2 concerts then the first album", y2019.Events)
+	}
+	for i, kind := range kinds {
+		if y2019.Events[i].Kind != kind {
+			t.Errorf("2019 event %d is a %s, want a %s", i, y2019.Events[i].Kind, kind)
+		}
+	}
+	if y2019.Events[0].Day() != "22-08-2019" || timeline.Years[0].Events[0].Day() != "1970" {
+		t.Errorf("event days = %s and %s, want 22-08-2019 and 1970", y2019.Events[0].Day(), timeline.Years[0].Events[0].Day())
+	}
+	if timeline.Years[0].Open || !timeline.Years[2].Open {
+		t.Errorf("only the last year should be expanded")
+	}
+
+	checkGolden(t, "timeline_queen.svg", timeline.SVG)
+}
+
+func TestArtistTimeline_Empty(t *testing.T) {
+	timeline := artistTimeline(Artist{Name: "Nobody", FirstAlbum: "unknown"}, nil)
+	if len(timeline.Years) != 0 {
+		t.Errorf("timeline years = %+v, want none", timeline.Years)
+	}
+	checkGolden(t, "timeline_empty.svg", timeline.SVG)
+}
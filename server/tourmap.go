@@ -0,0 +1,173 @@
+package server
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strings"
+)
+
+// Size of the tour map in SVG user units: two units per degree, equirectangular projection.
+const (
+	mapWidth  = 720
+	mapHeight = 360
+)
+
+// Coarse outlines of the land masses as longitude, latitude pairs. They only need to be
+// good enough to recognize where a concert took place, which keeps the map small and offline.
+var landOutlines = [][][2]float64{
+	// North America
+	{{-168, 66}, {-162, 70}, {-140, 70}, {-125, 70}, {-95, 72}, {-80, 73}, {-62, 66}, {-55, 52}, {-66, 44}, {-70, 41},
+		{-76, 35}, {-81, 31}, {-80, 25}, {-82, 27}, {-84, 30}, {-90, 29}, {-97, 27}, {-97, 22}, {-92, 18}, {-87, 21},
+		{-88, 16}, {-83, 10}, {-78, 8}, {-80, 9}, {-86, 12}, {-92, 15}, {-105, 20}, {-110, 24}, {-112, 30}, {-117, 33},
+		{-122, 37}, {-124, 42}, {-124, 48}, {-130, 55}, {-140, 60}, {-150, 60}, {-158, 57}, {-165, 60}},
+	// Greenland
+	{{-52, 60}, {-42, 60}, {-20, 70}, {-18, 78}, {-30, 83}, {-55, 82}, {-70, 78}, {-58, 70}},
+	// Iceland
+	{{-24, 65}, {-14, 66}, {-14, 64}, {-22, 63}},
+	// South America
+	{{-78, 8}, {-72, 12}, {-62, 10}, {-52, 5}, {-50, 0}, {-35, -6}, {-39, -14}, {-41, -22}, {-48, -26}, {-53, -34},
+		{-58, -38}, {-62, -41}, {-65, -45}, {-68, -52}, {-70, -55}, {-75, -50}, {-73, -40}, {-71, -30}, {-70, -18},
+		{-76, -14}, {-81, -5}, {-80, 0}, {-77, 4}},
+	// Europe and Asia
+	{{-10, 36}, {-9, 43}, {-2, 44}, {-5, 48}, {2, 51}, {8, 54}, {8, 57}, {5, 58}, {5, 62}, {12, 66}, {20, 70}, {28, 71},
+		{40, 68}, {45, 68}, {60, 69}, {70, 73}, {80, 73}, {100, 78}, {115, 74}, {130, 71}, {140, 72}, {160, 70},
+		{180, 68}, {180, 65}, {170, 60}, {160, 55}, {156, 51}, {142, 54}, {140, 48}, {133, 43}, {129, 35}, {126, 38},
+		{122, 40}, {119, 35}, {122, 31}, {120, 25}, {110, 20}, {108, 16}, {109, 12}, {105, 9}, {103, 1}, {100, 7},
+		{98, 16}, {94, 17}, {91, 22}, {86, 20}, {80, 15}, {78, 8}, {73, 18}, {72, 21}, {67, 25}, {57, 25}, {56, 27},
+		{52, 28}, {48, 30}, {50, 26}, {56, 24}, {59, 22}, {55, 17}, {45, 13}, {43, 13}, {39, 21}, {35, 28}, {34, 31},
+		{36, 36}, {30, 36}, {26, 38}, {26, 41}, {23, 37}, {20, 40}, {19, 42}, {13, 45}, {16, 41}, {18, 40}, {15, 38},
+		{12, 41}, {8, 44}, {3, 43}, {0, 39}, {-6, 37}},
+	// Great Britain and Ireland
+	{{-5, 50}, {1, 51}, {2, 53}, {-2, 56}, {-2, 58}, {-5, 59}, {-6, 56}, {-3, 54}, {-5, 52}},
+	{{-6, 52}, {-6, 55}, {-9, 55}, {-10, 52}},
+	// Africa and Madagascar
+	{{-17, 21}, {-10, 30}, {-6, 36}, {10, 37}, {11, 33}, {20, 31}, {30, 31}, {34, 28}, {38, 18}, {43, 12}, {51, 12},
+		{49, 7}, {40, -3}, {40, -15}, {35, -24}, {32, -29}, {27, -34}, {20, -35}, {18, -30}, {12, -18}, {13, -8},
+		{9, -1}, {9, 4}, {5, 5}, {-4, 5}, {-8, 4}, {-13, 8}, {-17, 14}},
+	{{44, -25}, {47, -25}, {50, -15}, {49, -12}, {44, -16}},
+	// Japan
+	{{130, 31}, {135, 34}, {140, 35}, {142, 40}, {141, 45}, {145, 44}, {141, 41}, {139, 38}, {136, 36}, {131, 34}},
+	// Sumatra, Java and Borneo
+	{{95, 5}, {98, 4}, {106, -6}, {104, -6}, {100, -2}},
+	{{105, -7}, {114, -8}, {114, -7}, {106, -6}},
+	{{109, 2}, {117, 7}, {119, 5}, {116, -4}, {110, -3}},
+	// Australia and New Zealand
+	{{114, -22}, {122, -18}, {130, -12}, {137, -12}, {136, -15}, {141, -11}, {146, -19}, {153, -25}, {151, -33},
+		{150, -37}, {144, -38}, {138, -35}, {132, -32}, {124, -34}, {115, -34}},
+	{{172, -34}, {178, -38}, {175, -41}, {171, -46}, {167, -46}, {172, -41}},
+}
+
+// project converts a longitude and latitude into map coordinates.
+func project(lon, lat float64) (x, y float64) {
+	return (lon + 180) * mapWidth / 360, (90 - lat) * mapHeight / 180
+}
+
+// mapStop is a location on the tour map with every date played there.
+type mapStop struct {
+	lon, lat float64
+	x, y     float64
+	name     string
+	dates    []string
+}
+
+// routeLines returns the polylines joining the stops in order, as map coordinates.
+// A leg spanning more than half the world takes the short way across the
+// antimeridian: it leaves the map on one side and comes back on the other.
+func routeLines(route []*mapStop) [][][2]float64 {
+	lines := [][][2]float64{{{route[0].x, route[0].y}}}
+	for i := 1; i < len(route); i++ {
+		from, to := route[i-1], route[i]
+		if delta := to.lon - from.lon; math.Abs(delta) > 180 {
+			// Eastwards the leg leaves at +180 towards the destination shifted a turn
+			// east, westwards at -180
+			edge, lon := 180.0, to.lon+360
+			if delta > 0 {
+				edge, lon = -180, to.lon-360
+			}
+			lat := from.lat + (to.lat-from.lat)*(edge-from.lon)/(lon-from.lon)
+			x, y := project(edge, lat)
+			lines[len(lines)-1] = append(lines[len(lines)-1], [2]float64{x, y})
+			x, y = project(-edge, lat)
+			lines = append(lines, [][2]float64{{x, y}})
+		}
+		lines[len(lines)-1] = append(lines[len(lines)-1], [2]float64{to.x, to.y})
+	}
+	return lines
+}
+
+// renderTourMap draws an SVG world map marking every concert location and joining
+// them in chronological order. Locations without coordinates are left out.
+func renderTourMap(title string, concerts []Concert) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" class="tour-map" role="img" aria-label="%s">`+"\n",
+		mapWidth, mapHeight, html.EscapeString(title))
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#262626"/>`+"\n", mapWidth, mapHeight)
+
+	// Graticule every 30 degrees
+	b.WriteString(`<g stroke="#3B3430" stroke-width="0.5">` + "\n")
+	for lon := -150; lon < 180; lon += 30 {
+		x, _ := project(float64(lon), 0)
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="0" x2="%.1f" y2="%d"/>`+"\n", x, x, mapHeight)
+	}
+	for lat := -60; lat <= 60; lat += 30 {
+		_, y := project(0, float64(lat))
+		fmt.Fprintf(&b, `<line x1="0" y1="%.1f" x2="%d" y2="%.1f"/>`+"\n", y, mapWidth, y)
+	}
+	b.WriteString("</g>\n")
+
+	b.WriteString(`<g fill="#4a4542" stroke="#5c5652" stroke-width="0.5">` + "\n")
+	for _, outline := range landOutlines {
+		b.WriteString(`<polygon points="`)
+		for i, p := range outline {
+			x, y := project(p[0], p[1])
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			fmt.Fprintf(&b, "%.1f,%.1f", x, y)
+		}
+		b.WriteString(`"/>` + "\n")
+	}
+	b.WriteString("</g>\n")
+
+	// Collect the stops and the route, skipping repeated shows in the same place
+	var stops []*mapStop
+	bySlug := make(map[string]*mapStop)
+	var route []*mapStop
+	for _, c := range locateConcerts(concerts) {
+		stop, ok := bySlug[c.Location]
+		if !ok {
+			x, y := project(c.Place.Lon, c.Place.Lat)
+			stop = &mapStop{lon: c.Place.Lon, lat: c.Place.Lat, x: x, y: y, name: c.LocationName()}
+			bySlug[c.Location] = stop
+			stops = append(stops, stop)
+		}
+		stop.dates = append(stop.dates, c.Date.Format(dateLayout))
+		if len(route) == 0 || route[len(route)-1] != stop {
+			route = append(route, stop)
+		}
+	}
+
+	if len(route) > 1 {
+		for _, line := range routeLines(route) {
+			b.WriteString(`<polyline fill="none" stroke="#F2EF72" stroke-width="1.5" stroke-linejoin="round" stroke-opacity="0.8" points="`)
+			for i, p := range line {
+				if i > 0 {
+					b.WriteByte(' ')
+				}
+				fmt.Fprintf(&b, "%.1f,%.1f", p[0], p[1])
+			}
+			b.WriteString(`"/>` + "\n")
+		}
+	}
+
+	b.WriteString(`<g fill="#ed2100" stroke="#f2f0ef" stroke-width="1">` + "\n")
+	for _, stop := range stops {
+		fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="4"><title>%s: %s</title></circle>`+"\n",
+			stop.x, stop.y, html.EscapeString(stop.name), strings.Join(stop.dates, ", "))
+	}
+	b.WriteString("</g>\n")
+
+	b.WriteString("</svg>")
+	return b.String()
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"groupie-tracker/gazetteer"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// checkGolden compares output with a golden file in testdata, rewriting it when -update is set.
+func checkGolden(t *testing.T, name, output string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(output), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read golden file (run with -update to create it): %v", err)
+	}
+	if output != string(expected) {
+		t.Errorf("output does not match %s (run with -update to accept it):\n%s", path, output)
+	}
+}
+
+func TestRenderTourMap(t *testing.T) {
+	places = gazetteer.Default()
+	list, rels := concertData()
+	rels[1].DatesLocation["osaka-japan"] = []string{"28-01-2020"}
+	useData(list, rels)
+
+	tests := []struct {
+		name     string
+		artistID int
+		golden   string
+	}{
+		{"Tour with route", 1, "tourmap_queen.svg"},
+		{"Single stop", 2, "tourmap_pink_floyd.svg"},
+		{"No concerts", 3, "tourmap_empty.svg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var concerts []Concert
+			if tt.artistID <= len(list) {
+				artist := list[tt.artistID-1]
+				concerts = concertsOf(artist, rels[artist.ID])
+			}
+			svg := renderTourMap("Tour map", concerts)
+
+			// The map has to work offline: no scripts and no external resources
+			if strings.Contains(svg, "<script") || strings.Count(svg, "http") != 1 {
+				t.Errorf("map references scripts or external resources")
+			}
+			checkGolden(t, tt.golden, svg)
+		})
+	}
+}
+
+func TestRouteLines(t *testing.T) {
+	stop := func(lon, lat float64) *mapStop {
+		x, y := project(lon, lat)
+		return &mapStop{lon: lon, lat: lat, x: x, y: y}
+	}
+	tokyo, losAngeles, paris := stop(140, 36), stop(-118, 34), stop(2, 49)
+
+	// Tokyo to Los Angeles crosses the Pacific, leaving on the right and coming back on the left
+	lines := routeLines([]*mapStop{paris, tokyo, losAngeles, paris})
+	if len(lines) != 2 {
+		t.Fatalf("routeLines() = %v, want the route split in two at the antimeridian", lines)
+	}
+	exit, entry := lines[0][len(lines[0])-1], lines[1][0]
+	if exit[0] != mapWidth || entry[0] != 0 || exit[1] != entry[1] {
+		t.Errorf("route leaves at %v and comes back at %v, want opposite edges at the same latitude", exit, entry)
+	}
+	for _, line := range lines {
+		for i := 1; i < len(line); i++ {
+			if math.Abs(line[i][0]-line[i-1][0]) > mapWidth/2 {
+				t.Errorf("segment %v to %v spans more than half the map", line[i-1], line[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+	"time"
+)
+
+// SetNow makes the pages treat the given time as the current one instead of the
+// clock, so that upcoming and past concerts stay the same from run to run.
+func SetNow(t time.Time) {
+	now = func() time.Time { return t }
+}
+
+// today returns the start of the current day, the boundary between past and upcoming concerts.
+func today() time.Time {
+	t := now()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// splitConcerts separates sorted concerts into the upcoming ones, soonest first,
+// and the past ones, most recent first. Concerts on the given day are upcoming.
+func splitConcerts(concerts []Concert, day time.Time) (upcoming, past []Concert) {
+	for _, c := range concerts {
+		if c.Date.Before(day) {
+			past = append(past, c)
+		} else {
+			upcoming = append(upcoming, c)
+		}
+	}
+	for i, j := 0, len(past)-1; i < j; i, j = i+1, j-1 {
+		past[i], past[j] = past[j], past[i]
+	}
+	return upcoming, past
+}
+
+// nextShows returns the next concert of every artist who has one, keyed by artist ID.
+func nextShows(concerts []Concert, day time.Time) map[int]*Concert {
+	next := make(map[int]*Concert)
+	for i, c := range concerts {
+		if c.Date.Before(day) {
+			continue
+		}
+		if _, ok := next[c.ArtistID]; !ok {
+			next[c.ArtistID] = &concerts[i]
+		}
+	}
+	return next
+}
+
+// UpcomingPage lists the upcoming concerts of all artists by month.
+func UpcomingPage(w http.ResponseWriter, r *http.Request) {
+	d := currentData()
+	concerts, err := d.allConcerts(r.Context())
+	if err != nil {
+		loggerFrom(r.Context()).Error("could not list the concerts", "err", err)
+		ErrorPage(w, http.StatusInternalServerError)
+		return
+	}
+
+	upcoming, _ := splitConcerts(concerts, today())
+	data := TemplateData{
+		Title: "Upcoming Concerts",
+		Date: DateDetails{
+			Heading:  "Upcoming Concerts",
+			Concerts: len(upcoming),
+			Groups:   groupConcerts(upcoming, byMonth),
+		},
+		dataset: d,
+	}
+	renderTemplate(w, "upcoming.html", data)
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"groupie-tracker/gazetteer"
+)
+
+func TestSplitConcerts(t *testing.T) {
+	setupConcertData()
+	concerts, err := currentData().allConcerts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name             string
+		day              time.Time
+		expectedUpcoming []string
+		expectedPast     []string
+	}{
+		{"Before the first concert", time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC), []string{"22-08-2019", "23-08-2019", "05-07-2020", "01-01-2021"}, nil},
+		{"On a concert day", time.Date(2019, time.August, 23, 0, 0, 0, 0, time.UTC), []string{"23-08-2019", "05-07-2020", "01-01-2021"}, []string{"22-08-2019"}},
+		{"After the last concert", time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC), nil, []string{"01-01-2021", "05-07-2020", "23-08-2019", "22-08-2019"}},
+	}
+
+	days := func(concerts []Concert) []string {
+		var list []string
+		for _, c := range concerts {
+			list = append(list, c.Day())
+		}
+		return list
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upcoming, past := splitConcerts(concerts, tt.day)
+			if got := days(upcoming); strings.Join(got, " ") != strings.Join(tt.expectedUpcoming, " ") {
+				t.Errorf("upcoming = %v, want %v", got, tt.expectedUpcoming)
+			}
+			if got := days(past); strings.Join(got, " ") != strings.Join(tt.expectedPast, " ") {
+				t.Errorf("past = %v, want %v", got, tt.expectedPast)
+			}
+		})
+	}
+}
+
+func TestNextShows(t *testing.T) {
+	setupConcertData()
+	concerts, err := currentData().allConcerts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := nextShows(concerts, time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC))
+	if next[1] == nil || next[1].Day() != "05-07-2020" {
+		t.Errorf("next Queen show = %v, want 05-07-2020", next[1])
+	}
+	if next[2] == nil || next[2].Day() != "01-01-2021" {
+		t.Errorf("next Pink Floyd show = %v, want 01-01-2021", next[2])
+	}
+
+	next = nextShows(concerts, time.Date(2020, time.December, 1, 0, 0, 0, 0, time.UTC))
+	if _, ok := next[1]; ok {
+		t.Errorf("Queen has a next show after their last concert")
+	}
+}
+
+func TestUpcomingPage(t *testing.T) {
+	setupConcertData()
+	places = gazetteer.Default()
+	useTemplates(t, "upcoming.html")
+	SetNow(time.Date(2020, time.July, 5, 18, 30, 0, 0, time.UTC))
+	defer func() { now = time.Now }()
+
+	r := httptest.NewRequest(http.MethodGet, "/upcoming", nil)
+	w := httptest.NewRecorder()
+	UpcomingPage(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("UpcomingPage() status code = %d, want %d", w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	for _, text := range []string{"2 concerts to come", "July 2020", "Saint Denis, France", "January 2021", "Pink Floyd"} {
+		if !strings.Contains(body, text) {
+			t.Errorf("UpcomingPage() response doesn't contain %q", text)
+		}
+	}
+	if strings.Contains(body, "23-08-2019") {
+		t.Errorf("UpcomingPage() lists a past concert")
+	}
+}
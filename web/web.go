@@ -0,0 +1,38 @@
+// Package web holds the HTML templates and the static files of the site, embedded
+// in the binary so that it runs from any directory.
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+)
+
+//go:embed templates/*.html
+var templates embed.FS
+
+//go:embed static
+var static embed.FS
+
+// Templates returns the HTML templates, read from dir on disk when it is not empty.
+func Templates(dir string) fs.FS {
+	return open(templates, "templates", dir)
+}
+
+// Static returns the files served under /static/, read from dir on disk when it is not empty.
+func Static(dir string) fs.FS {
+	return open(static, "static", dir)
+}
+
+// open returns the embedded directory name, or dir on disk when it is not empty.
+func open(embedded embed.FS, name, dir string) fs.FS {
+	if dir != "" {
+		return os.DirFS(dir)
+	}
+	sub, err := fs.Sub(embedded, name)
+	if err != nil {
+		// The directory is embedded at build time, so it is always there
+		panic(err)
+	}
+	return sub
+}
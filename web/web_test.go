@@ -0,0 +1,35 @@
+package web
+
+import (
+	"io/fs"
+	"testing"
+)
+
+func TestEmbedded(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fs.FS
+		file string
+	}{
+		{"Layout", Templates(""), "layout.html"},
+		{"Error page", Templates(""), "errors.html"},
+		{"Style sheet", Static(""), "style.css"},
+		{"Script", Static(""), "script.js"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := fs.Stat(tt.fsys, tt.file); err != nil {
+				t.Errorf("%s is not embedded: %v", tt.file, err)
+			}
+		})
+	}
+}
+
+func TestFromDisk(t *testing.T) {
+	if _, err := fs.Stat(Templates("templates"), "layout.html"); err != nil {
+		t.Errorf("Templates() from disk: %v", err)
+	}
+	if _, err := fs.Stat(Static(t.TempDir()), "style.css"); err == nil {
+		t.Error("Static() from an empty directory found style.css")
+	}
+}